@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/spaceapi/validator/config"
+	"github.com/spaceapi/validator/lint"
+	"github.com/spaceapi/validator/schema"
+	"github.com/spaceapi/validator/v2"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Exit codes of the command-line subcommands
+const (
+	exitValid   = 0
+	exitInvalid = 1
+	exitError   = 2
+)
+
+// check validates SpaceAPI documents read from the given files, or from
+// stdin if no file (or "-") is given, and returns the exit code
+func check(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: validator check [flags] [file...]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "text", "output format (text, json, junit, sarif or github)")
+	versionList := fs.String("versions", "", "comma separated SpaceAPI versions to validate against instead of the declared ones")
+	schemaDir, err := config.LoadSchemaDir(fs, args)
+	if err != nil {
+		return configError(stderr, err)
+	}
+
+	if *format != "text" && v2.ContentType(*format) == "" {
+		fs.Usage()
+		return exitError
+	}
+
+	schemas, err := loadSchemas(schemaDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	versions, err := schemas.ParseVersions(schema.SplitVersions(*versionList))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	code := exitValid
+	var reports []v2.Report
+	for _, file := range files {
+		body, err := readInput(file, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			code = exitError
+			continue
+		}
+
+		resp, err := v2.ValidateJSON(body, v2.ValidationOptions{Versions: versions, Schemas: schemas})
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			code = exitError
+			continue
+		}
+
+		reports = append(reports, v2.Report{Name: displayName(file), Result: resp})
+		if !resp.Valid && code == exitValid {
+			code = exitInvalid
+		}
+	}
+
+	if *format == "text" {
+		for _, report := range reports {
+			printJSONResult(stdout, report)
+		}
+		return code
+	}
+
+	err = v2.WriteReports(stdout, *format, reports)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	return code
+}
+
+// checkURL validates the given SpaceAPI endpoints like /v2/validateURL does
+// and returns the exit code
+func checkURL(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check-url", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: validator check-url [flags] url...")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "table", "output format (table or json)")
+	versionList := fs.String("versions", "", "comma separated SpaceAPI versions to validate against instead of the declared ones")
+	deep := fs.Bool("deep", false, "fetch the resources the endpoint links to and verify CORS with preflights")
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return configError(stderr, err)
+	}
+
+	if fs.NArg() == 0 || (*format != "table" && *format != "json") {
+		fs.Usage()
+		return exitError
+	}
+
+	schemas, err := loadSchemas(cfg.SchemaDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	versions, err := schemas.ParseVersions(schema.SplitVersions(*versionList))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	code := exitValid
+	var results []urlResult
+	for _, rawURL := range fs.Args() {
+		u, err := url.ParseRequestURI(rawURL)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", rawURL, err)
+			code = exitError
+			continue
+		}
+
+		resp, err := v2.ValidateURL(context.Background(), cfg, u, v2.ValidationOptions{Versions: versions, Schemas: schemas, Deep: *deep})
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", rawURL, err)
+			code = exitError
+			continue
+		}
+
+		results = append(results, urlResult{URL: rawURL, Result: resp})
+		if !resp.Valid && code == exitValid {
+			code = exitInvalid
+		}
+	}
+
+	if *format == "json" {
+		if results == nil {
+			results = []urlResult{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(results)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		return code
+	}
+
+	printURLResults(stdout, results)
+	return code
+}
+
+// configError reports an error returned by config.Load, unless the flag set
+// printed it already, and returns the exit code
+func configError(stderr io.Writer, err error) int {
+	if err == flag.ErrHelp {
+		return exitValid
+	}
+	if _, ok := err.(*config.ParseError); !ok {
+		fmt.Fprintln(stderr, err)
+	}
+	return exitError
+}
+
+// urlResult is the result of an endpoint given to check-url. The results are
+// printed as a list in the order of the arguments, so duplicate URLs are kept.
+type urlResult struct {
+	URL    string                   `json:"url"`
+	Result v2.URLValidationResponse `json:"result"`
+}
+
+// checkDirectory validates the endpoints listed in a SpaceAPI directory, read
+// from a file or URL, like /v2/validateDirectory does and returns the exit
+// code
+func checkDirectory(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check-directory", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: validator check-directory [flags] file|url")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "table", "output format (table or json)")
+	versionList := fs.String("versions", "", "comma separated SpaceAPI versions to validate against instead of the declared ones")
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return configError(stderr, err)
+	}
+
+	if fs.NArg() != 1 || (*format != "table" && *format != "json") {
+		fs.Usage()
+		return exitError
+	}
+
+	schemas, err := loadSchemas(cfg.SchemaDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	versions, err := schemas.ParseVersions(schema.SplitVersions(*versionList))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	source := fs.Arg(0)
+	directory, err := readDirectory(cfg, source)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", source, err)
+		return exitError
+	}
+
+	report := v2.ValidateDirectory(context.Background(), cfg, directory, v2.ValidationOptions{Versions: versions, Schemas: schemas})
+
+	code := exitValid
+	if report.Results[v2.ResultValid] < report.Total {
+		code = exitInvalid
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		return code
+	}
+
+	printDirectoryReport(stdout, report)
+	return code
+}
+
+// readDirectory reads a directory from a file or, if source is an http(s)
+// URL, fetches it
+func readDirectory(cfg config.Config, source string) (map[string]string, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		u, err := url.ParseRequestURI(source)
+		if err != nil {
+			return nil, err
+		}
+		return v2.FetchDirectory(context.Background(), cfg, u)
+	}
+
+	body, err := readInput(source, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	return v2.ParseDirectory(body)
+}
+
+func printDirectoryReport(w io.Writer, report v2.DirectoryReport) {
+	fmt.Fprintf(w, "%d spaces:", report.Total)
+	for i, result := range []string{v2.ResultValid, v2.ResultInvalid, v2.ResultUnreachable, v2.ResultError} {
+		separator := ","
+		if i == 0 {
+			separator = ""
+		}
+		fmt.Fprintf(w, "%s %d %s", separator, report.Results[result], result)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nVERSION\tSPACES")
+	for _, version := range report.Versions {
+		fmt.Fprintf(tw, "%s\t%d\n", version.Name, version.Count)
+	}
+	fmt.Fprintln(tw, "\nFAILED CHECK\tSPACES")
+	for _, check := range report.FailedChecks {
+		fmt.Fprintf(tw, "%s\t%d\n", check.Name, check.Count)
+	}
+	_ = tw.Flush()
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nSPACE\tRESULT\tVERSIONS\tFAILED CHECKS\tSCHEMA ERRORS\tMESSAGE")
+	for _, space := range report.Spaces {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			space.Space, space.Result, strings.Join(space.Versions, ","), strings.Join(space.FailedChecks, ","), space.SchemaErrors, space.Message)
+	}
+	_ = tw.Flush()
+}
+
+// migrateFile migrates a SpaceAPI document read from a file, or stdin if no
+// file (or "-") is given, writes the result to stdout and the changes to
+// stderr and returns the exit code
+func migrateFile(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: validator migrate [flags] [file]")
+		fs.PrintDefaults()
+	}
+	to := fs.String("to", "", "SpaceAPI version to migrate to (default: the newest stable version)")
+	schemaDir, err := config.LoadSchemaDir(fs, args)
+	if err != nil {
+		return configError(stderr, err)
+	}
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+	file := fs.Arg(0)
+	if file == "" {
+		file = "-"
+	}
+
+	schemas, err := loadSchemas(schemaDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	body, err := readInput(file, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", file, err)
+		return exitError
+	}
+
+	resp, err := v2.Migrate(body, *to, schemas)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", displayName(file), err)
+		return exitError
+	}
+
+	document, err := v2.MarshalDocument(resp.Document)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	fmt.Fprintln(stdout, string(document))
+
+	fmt.Fprintf(stderr, "%s: migrated from v%s to v%s\n", displayName(file), resp.From, resp.To)
+	for _, change := range resp.Changes {
+		fmt.Fprintf(stderr, "  %s\n", change)
+	}
+	for _, todo := range resp.Todos {
+		fmt.Fprintf(stderr, "  todo %s\n", todo)
+	}
+	for _, schemaError := range resp.SchemaErrors {
+		fmt.Fprintf(stderr, "  %s\n", schemaError)
+	}
+
+	if !resp.Valid || len(resp.Todos) > 0 {
+		return exitInvalid
+	}
+	return exitValid
+}
+
+func printURLResults(w io.Writer, results []urlResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tVALID\tHTTPS\tHTTPS FORWARD\tREACHABLE\tCORS\tCONTENT TYPE\tCERT VALID")
+	for _, result := range results {
+		r := result.Result
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			result.URL, r.Valid, r.IsHTTPS, r.HTTPSForward, r.Reachable, r.Cors, r.ContentType, r.CertValid)
+	}
+	_ = tw.Flush()
+
+	for _, result := range results {
+		r := result.Result
+		var brokenLinks []v2.LinkReport
+		for _, link := range r.Links {
+			if link.Error != "" {
+				brokenLinks = append(brokenLinks, link)
+			}
+		}
+		if len(r.SchemaErrors) == 0 && len(r.Warnings) == 0 && len(brokenLinks) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%s:\n", result.URL)
+		for _, schemaError := range r.SchemaErrors {
+			fmt.Fprintf(w, "  %s\n", schemaError)
+		}
+		printWarnings(w, r.Warnings)
+		for _, link := range brokenLinks {
+			fmt.Fprintf(w, "  %s %s: %s\n", link.Pointer, link.URL, link.Error)
+		}
+	}
+}
+
+// loadSchemas returns the schemas of dir, or the builtin schemas if dir is
+// empty
+func loadSchemas(dir string) (*schema.Set, error) {
+	if dir == "" {
+		return schema.Builtin(), nil
+	}
+	return schema.LoadDir(dir)
+}
+
+func readInput(file string, stdin io.Reader) ([]byte, error) {
+	if file == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(file)
+}
+
+func printJSONResult(w io.Writer, report v2.Report) {
+	versions := strings.Join(report.Result.CheckedVersions, ", ")
+	if report.Result.Valid {
+		fmt.Fprintf(w, "%s: valid (checked versions: %s)\n", report.Name, versions)
+		printWarnings(w, report.Result.Warnings)
+		return
+	}
+
+	fmt.Fprintf(w, "%s: invalid (checked versions: %s)\n", report.Name, versions)
+	for _, schemaError := range report.Result.SchemaErrors {
+		fmt.Fprintf(w, "  %s\n", schemaError)
+	}
+	printWarnings(w, report.Result.Warnings)
+}
+
+func printWarnings(w io.Writer, warnings []lint.Warning) {
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  %s\n", warning)
+	}
+}
+
+func displayName(file string) string {
+	if file == "-" {
+		return "<stdin>"
+	}
+	return file
+}
+
+// runCommand runs the subcommand named by args[0], if there is one
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	switch args[0] {
+	case "check":
+		return check(args[1:], os.Stdin, os.Stdout, os.Stderr), true
+	case "check-url":
+		return checkURL(args[1:], os.Stdout, os.Stderr), true
+	case "check-directory":
+		return checkDirectory(args[1:], os.Stdout, os.Stderr), true
+	case "migrate":
+		return migrateFile(args[1:], os.Stdin, os.Stdout, os.Stderr), true
+	}
+
+	return 0, false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: validator [flags]                     start the validation server")
+	fmt.Fprintln(out, "       validator check [flags] [file...]    validate SpaceAPI documents")
+	fmt.Fprintln(out, "       validator check-url [flags] url...   validate SpaceAPI endpoints")
+	fmt.Fprintln(out, "       validator check-directory [flags] file|url")
+	fmt.Fprintln(out, "                                            validate the endpoints of a SpaceAPI directory")
+	fmt.Fprintln(out, "       validator migrate [flags] [file]     migrate a SpaceAPI document to a newer version")
+	fmt.Fprintln(out)
+	flag.PrintDefaults()
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/spaceapi/validator/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var validSpace = `{
+	"api_compatibility": ["14"],
+	"space": "my cool space",
+	"logo": "https://example.com/logo.png",
+	"url": "https://example.com",
+	"location": {
+		"lon": 9.236,
+		"lat": 48.777
+	},
+	"contact": {
+		"email": "info@example.com"
+	}
+}`
+
+var invalidSpace = `{
+	"api_compatibility": ["14"],
+	"space": "my cool space"
+}`
+
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestCheckValidFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeTempFile(t, dir, "valid.json", validSpace)
+
+	var stdout, stderr bytes.Buffer
+	code := check([]string{path}, nil, &stdout, &stderr)
+
+	if code != exitValid {
+		t.Errorf("wrong exit code: got %v want %v (%s)", code, exitValid, stderr.String())
+	}
+
+	if !strings.Contains(stdout.String(), "valid") {
+		t.Errorf("output does not contain the result: %s", stdout.String())
+	}
+}
+
+func TestCheckInvalidFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	valid := writeTempFile(t, dir, "valid.json", validSpace)
+	invalid := writeTempFile(t, dir, "invalid.json", invalidSpace)
+
+	var stdout, stderr bytes.Buffer
+	code := check([]string{valid, invalid}, nil, &stdout, &stderr)
+
+	if code != exitInvalid {
+		t.Errorf("wrong exit code: got %v want %v", code, exitInvalid)
+	}
+
+	if !strings.Contains(stdout.String(), invalid+": invalid") {
+		t.Errorf("output does not mark the invalid file: %s", stdout.String())
+	}
+
+	if !strings.Contains(stdout.String(), "(root): ") {
+		t.Errorf("output does not contain the schema errors: %s", stdout.String())
+	}
+}
+
+func TestCheckStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := check(nil, strings.NewReader(invalidSpace), &stdout, &stderr)
+
+	if code != exitInvalid {
+		t.Errorf("wrong exit code: got %v want %v", code, exitInvalid)
+	}
+
+	if !strings.HasPrefix(stdout.String(), "<stdin>: invalid") {
+		t.Errorf("wrong output: %s", stdout.String())
+	}
+}
+
+func TestCheckMissingFile(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := check([]string{"does-not-exist.json"}, nil, &stdout, &stderr)
+
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+}
+
+func TestCheckMalformedJson(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := check(nil, strings.NewReader("foo"), &stdout, &stderr)
+
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/logo.png" {
+				w.Header().Add("Content-Type", "image/png")
+				return
+			}
+			// link the logo to the test server, so it is checked without
+			// network access
+			space := strings.Replace(validSpace, "https://example.com/logo.png", "http://"+r.Host+"/logo.png", 1)
+			w.Header().Add("Access-Control-Allow-Origin", "*")
+			w.Header().Add("Content-Type", "application/json")
+			_, _ = w.Write([]byte(space))
+		}))
+	defer ts.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := checkURL([]string{"-format", "json", "-fetch-allow", "127.0.0.1", ts.URL + "/other", ts.URL, ts.URL + "/other"}, &stdout, &stderr)
+
+	if code != exitValid {
+		t.Errorf("wrong exit code: got %v want %v (%s)", code, exitValid, stderr.String())
+	}
+
+	var results []urlResult
+	err := json.NewDecoder(&stdout).Decode(&results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 || results[0].URL != ts.URL+"/other" || results[1].URL != ts.URL || results[2].URL != ts.URL+"/other" {
+		t.Fatalf("results are not in the order of the arguments: %+v", results)
+	}
+	resp := results[1].Result
+
+	if !resp.Reachable || !resp.Cors || !resp.ContentType {
+		t.Errorf("wrong checks: got %+v", resp)
+	}
+
+	if len(resp.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %+v", resp.Warnings)
+	}
+}
+
+func TestCheckURLTable(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(invalidSpace))
+		}))
+	defer ts.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := checkURL([]string{"-fetch-allow", "127.0.0.1", ts.URL}, &stdout, &stderr)
+
+	if code != exitInvalid {
+		t.Errorf("wrong exit code: got %v want %v", code, exitInvalid)
+	}
+
+	if !strings.HasPrefix(stdout.String(), "URL ") {
+		t.Errorf("output does not start with the table header: %s", stdout.String())
+	}
+
+	if !strings.Contains(stdout.String(), "(root): ") {
+		t.Errorf("output does not contain the schema errors: %s", stdout.String())
+	}
+}
+
+func TestCheckURLInvalidURL(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := checkURL([]string{"not a url"}, &stdout, &stderr)
+
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+}
+
+func TestCheckFormatGitHub(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := check([]string{"-format", "github"}, strings.NewReader(invalidSpace), &stdout, &stderr)
+
+	if code != exitInvalid {
+		t.Errorf("wrong exit code: got %v want %v", code, exitInvalid)
+	}
+
+	if !strings.HasPrefix(stdout.String(), "::error file=<stdin>,") {
+		t.Errorf("wrong output: %s", stdout.String())
+	}
+}
+
+func TestCheckUnknownFormat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := check([]string{"-format", "yaml"}, strings.NewReader(validSpace), &stdout, &stderr)
+
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+}
+
+func TestCheckForcedVersions(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeTempFile(t, dir, "valid.json", validSpace)
+
+	var stdout, stderr bytes.Buffer
+	check([]string{"-versions", "14,15", path}, nil, &stdout, &stderr)
+
+	if !strings.Contains(stdout.String(), "checked versions: 14, 15") {
+		t.Errorf("output does not contain the forced versions: %s", stdout.String())
+	}
+
+	code := check([]string{"-versions", "99", path}, nil, &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+	if !strings.Contains(stderr.String(), `unknown SpaceAPI version "99"`) {
+		t.Errorf("output does not contain the error: %s", stderr.String())
+	}
+}
+
+func TestCheckSchemaDir(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeTempFile(t, dir, "17-draft.json", `{"type": "object", "required": ["rocket"]}`)
+	path := writeTempFile(t, dir, "space.json", validSpace)
+
+	var stdout, stderr bytes.Buffer
+	code := check([]string{"-schema-dir", dir, "-versions", "17", path}, nil, &stdout, &stderr)
+
+	if code != exitInvalid {
+		t.Errorf("wrong exit code: got %v want %v (%s)", code, exitInvalid, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "rocket") {
+		t.Errorf("output does not contain the schema error: %s", stdout.String())
+	}
+}
+
+func TestCheckSchemaDirEnv(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeTempFile(t, dir, "15.json", `{"type": "object", "required": ["rocket"]}`)
+	path := writeTempFile(t, dir, "space.json", validSpace)
+
+	// -schema-dir is read from the environment like the other config flags
+	err := os.Setenv(config.EnvName("schema-dir"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(config.EnvName("schema-dir"))
+
+	for name, run := range map[string]func() (int, string){
+		"check": func() (int, string) {
+			var stdout, stderr bytes.Buffer
+			return check([]string{"-versions", "15", path}, nil, &stdout, &stderr), stdout.String()
+		},
+		"migrate": func() (int, string) {
+			var stdout, stderr bytes.Buffer
+			return migrateFile([]string{"-to", "15", path}, nil, &stdout, &stderr), stderr.String()
+		},
+	} {
+		code, output := run()
+		if code != exitInvalid {
+			t.Errorf("%s: wrong exit code: got %v want %v (%s)", name, code, exitInvalid, output)
+		}
+		if !strings.Contains(output, "rocket") {
+			t.Errorf("%s: output does not contain the schema error: %s", name, output)
+		}
+	}
+}
+
+func TestCheckConfigErrors(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	invalid := writeTempFile(t, dir, "invalid.json", `{"rate-limit": "x"}`)
+	unknown := writeTempFile(t, dir, "unknown.json", `{"rocket": true}`)
+	path := writeTempFile(t, dir, "space.json", validSpace)
+
+	tests := map[string][]string{
+		"invalid value":  {"-config", invalid, path},
+		"unknown option": {"-config", unknown, path},
+		"missing file":   {"-config", filepath.Join(dir, "missing.json"), path},
+	}
+
+	for name, args := range tests {
+		var stdout, stderr bytes.Buffer
+		code := checkURL(args, &stdout, &stderr)
+
+		if code != exitError {
+			t.Errorf("%s: wrong exit code: got %v want %v", name, code, exitError)
+		}
+		if stderr.Len() == 0 {
+			t.Errorf("%s: the error was not reported", name)
+		}
+	}
+
+	// parse errors are reported by the flag package, only once
+	var stdout, stderr bytes.Buffer
+	code := check([]string{"-unknown-flag"}, nil, &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+	if n := strings.Count(stderr.String(), "-unknown-flag"); n != 1 {
+		t.Errorf("parse error was reported %d times: %s", n, stderr.String())
+	}
+}
+
+func TestCheckJobSettingsIgnored(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(validSpace))
+		}))
+	defer ts.Close()
+
+	// the job queue only runs in the server, its settings are checked there
+	err := os.Setenv(config.EnvName("job-workers"), "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(config.EnvName("job-workers"))
+
+	var stdout, stderr bytes.Buffer
+	code := checkURL([]string{"-fetch-allow", "127.0.0.1", ts.URL}, &stdout, &stderr)
+
+	if code != exitValid {
+		t.Errorf("wrong exit code: got %v want %v (%s)", code, exitValid, stderr.String())
+	}
+}
+
+func TestOfflineFlags(t *testing.T) {
+	for name, run := range map[string]func(args []string, stderr *bytes.Buffer) int{
+		"check": func(args []string, stderr *bytes.Buffer) int {
+			return check(args, nil, ioutil.Discard, stderr)
+		},
+		"migrate": func(args []string, stderr *bytes.Buffer) int {
+			return migrateFile(args, nil, ioutil.Discard, stderr)
+		},
+	} {
+		var stderr bytes.Buffer
+		if code := run([]string{"-h"}, &stderr); code != exitValid {
+			t.Errorf("%s: wrong exit code: got %v want %v", name, code, exitValid)
+		}
+		usage := stderr.String()
+		if !strings.Contains(usage, "-schema-dir") || !strings.Contains(usage, "-config") {
+			t.Errorf("%s: usage lacks the schema flags: %s", name, usage)
+		}
+		for _, flag := range []string{"-listen", "-job-queue", "-rate-burst"} {
+			if strings.Contains(usage, flag) {
+				t.Errorf("%s: usage lists server flag %s", name, flag)
+			}
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	stdin := strings.NewReader(`{
+	"api": "0.13",
+	"space": "my cool space",
+	"logo": "https://example.com/logo.png",
+	"url": "https://example.com/?a=1&b=2",
+	"location": {"lon": 9.236, "lat": 48.777},
+	"state": {"open": true},
+	"contact": {"email": "info@example.com"},
+	"issue_report_channels": ["email"]
+}`)
+
+	var stdout, stderr bytes.Buffer
+	code := migrateFile([]string{"-to", "14"}, stdin, &stdout, &stderr)
+
+	if code != exitValid {
+		t.Fatalf("wrong exit code: got %v want %v (%s)", code, exitValid, stderr.String())
+	}
+
+	var migrated map[string]interface{}
+	err := json.Unmarshal(stdout.Bytes(), &migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := migrated["issue_report_channels"]; ok {
+		t.Errorf("issue_report_channels was not removed: %s", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "?a=1&b=2") {
+		t.Errorf("url was escaped: %s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "migrated from v13 to v14") {
+		t.Errorf("output does not contain the changes: %s", stderr.String())
+	}
+}
+
+func TestMigrateUnsupported(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := migrateFile(nil, strings.NewReader(`{"api": "0.12"}`), &stdout, &stderr)
+
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+}
+
+func TestCheckDirectory(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/invalid.json" {
+				_, _ = w.Write([]byte(invalidSpace))
+				return
+			}
+			_, _ = w.Write([]byte(validSpace))
+		}))
+	defer ts.Close()
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeTempFile(t, dir, "directory.json", `{
+		"Valid Space": "`+ts.URL+`/status.json",
+		"Invalid Space": "`+ts.URL+`/invalid.json"
+	}`)
+
+	var stdout, stderr bytes.Buffer
+	code := checkDirectory([]string{"-fetch-allow", "127.0.0.1", path}, &stdout, &stderr)
+
+	if code != exitInvalid {
+		t.Errorf("wrong exit code: got %v want %v (%s)", code, exitInvalid, stderr.String())
+	}
+
+	for _, want := range []string{"2 spaces: 1 valid, 1 invalid", "Invalid Space", "Valid Space"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output does not contain %q: %s", want, stdout.String())
+		}
+	}
+}
+
+func TestCheckDirectoryMissingFile(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := checkDirectory([]string{"does-not-exist.json"}, &stdout, &stderr)
+
+	if code != exitError {
+		t.Errorf("wrong exit code: got %v want %v", code, exitError)
+	}
+}
@@ -0,0 +1,334 @@
+// Package config contains the runtime configuration of the validator
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/spaceapi/validator/ratelimit"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds all settings which can be changed per deployment
+type Config struct {
+	ListenAddr         string
+	MetricsListenAddr  string
+	CORSOrigins        []string
+	RateLimit          float64
+	RateBurst          int
+	JSONRateLimit      float64
+	JSONRateBurst      int
+	TrustedProxies     []*net.IPNet
+	APIKeys            []string
+	FetchTimeout       time.Duration
+	MaxRequestBodySize int64
+	MaxFetchBodySize   int64
+	Origin             string
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	IdleTimeout        time.Duration
+	ShutdownTimeout    time.Duration
+	FetchAllow         []*net.IPNet
+	CertWarningDays    int
+	MaxRedirects       int
+	SchemaDir          string
+	SchemaReload       time.Duration
+	JobWorkers         int
+	JobQueueSize       int
+	JobRetention       time.Duration
+	BatchParallelism   int
+	BatchMaxItems      int
+	DirectoryMaxSpaces int
+}
+
+// Default returns the configuration used when nothing else is specified
+func Default() Config {
+	return Config{
+		ListenAddr:         ":8080",
+		CORSOrigins:        []string{"*"},
+		RateLimit:          200,
+		RateBurst:          500,
+		JSONRateLimit:      200,
+		JSONRateBurst:      500,
+		FetchTimeout:       time.Second * 10,
+		MaxRequestBodySize: 1 << 20,
+		MaxFetchBodySize:   1 << 20,
+		Origin:             "https://validator.spaceapi.io",
+		ReadTimeout:        time.Second * 10,
+		WriteTimeout:       time.Minute,
+		IdleTimeout:        time.Minute * 2,
+		ShutdownTimeout:    time.Second * 30,
+		CertWarningDays:    14,
+		MaxRedirects:       10,
+		SchemaReload:       time.Second * 10,
+		JobWorkers:         4,
+		JobQueueSize:       100,
+		JobRetention:       time.Hour,
+		BatchParallelism:   4,
+		BatchMaxItems:      50,
+		DirectoryMaxSpaces: 500,
+	}
+}
+
+// LimitOptions returns how the rate limiters identify clients
+func (c Config) LimitOptions() ratelimit.Options {
+	return ratelimit.Options{
+		TrustedProxies: c.TrustedProxies,
+		APIKeys:        c.APIKeys,
+	}
+}
+
+const schemaDirUsage = "directory with SpaceAPI schema files (e.g. 15.json) used instead of the builtin ones of the same version"
+
+// EnvPrefix is prepended to the upper-cased flag name to get the name of the
+// environment variable overriding it, e.g. VALIDATOR_LISTEN for -listen
+const EnvPrefix = "VALIDATOR_"
+
+// Load registers the configuration flags on fs, parses args and returns the
+// resulting configuration. Values are taken from (in increasing order of
+// precedence) the defaults, the JSON config file given by -config, the
+// environment and the command-line. Callers may register their own flags on
+// fs beforehand and read fs.Args() afterwards.
+func Load(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+	cfg.bind(fs)
+
+	err := loadValues(fs, args, nil)
+	return cfg, err
+}
+
+// LoadSchemaDir is Load for subcommands which only need the schema
+// directory. Only -schema-dir and -config are registered on fs, the other
+// options are ignored in the config file and the environment.
+func LoadSchemaDir(fs *flag.FlagSet, args []string) (string, error) {
+	cfg := Default()
+	fs.StringVar(&cfg.SchemaDir, "schema-dir", cfg.SchemaDir, schemaDirUsage)
+
+	// the config file may be shared with the server
+	server := flag.NewFlagSet("server", flag.ContinueOnError)
+	other := Default()
+	other.bind(server)
+
+	err := loadValues(fs, args, server)
+	return cfg.SchemaDir, err
+}
+
+// loadValues registers -config on fs, parses args and sets the flags of fs which
+// weren't given from the config file and the environment. Options in the
+// config file which aren't flags of fs are an error, unless they are flags
+// of ignored.
+func loadValues(fs *flag.FlagSet, args []string, ignored *flag.FlagSet) error {
+	configFile := fs.String("config", "", "path to a JSON config file")
+
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return err
+	}
+	if err != nil {
+		return &ParseError{Err: err}
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if !explicit["config"] {
+		*configFile = os.Getenv(EnvName("config"))
+	}
+
+	values := map[string]string{}
+	if *configFile != "" {
+		values, err = readFile(*configFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if value, ok := os.LookupEnv(EnvName(f.Name)); ok {
+			values[f.Name] = value
+		}
+	})
+
+	for name, value := range values {
+		if explicit[name] || name == "config" {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			if ignored != nil && ignored.Lookup(name) != nil {
+				continue
+			}
+			return fmt.Errorf("unknown config option %q", name)
+		}
+		err = fs.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", value, name, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckServer rejects values the server can't run with. The subcommands don't
+// use them, so Load doesn't check them.
+func (c Config) CheckServer() error {
+	if c.JobWorkers <= 0 {
+		return fmt.Errorf("job-workers must be at least 1, got %d", c.JobWorkers)
+	}
+	if c.JobQueueSize < 0 {
+		return fmt.Errorf("job-queue can't be negative, got %d", c.JobQueueSize)
+	}
+	return nil
+}
+
+// ParseError is returned by Load if the command-line can't be parsed. The
+// flag set has reported it on its output already.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// EnvName returns the environment variable corresponding to a flag name
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+func (c *Config) bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.ListenAddr, "listen", c.ListenAddr, "address the http server listens on")
+	fs.StringVar(&c.MetricsListenAddr, "metrics-listen", c.MetricsListenAddr, "address the Prometheus metrics are served on, separate from the API; metrics are disabled if empty")
+	fs.Var((*stringList)(&c.CORSOrigins), "cors-origins", "comma separated list of allowed CORS origins")
+	fs.Float64Var(&c.RateLimit, "rate-limit", c.RateLimit, "allowed URL validations per second and client")
+	fs.IntVar(&c.RateBurst, "rate-burst", c.RateBurst, "maximum burst of URL validations per client")
+	fs.Float64Var(&c.JSONRateLimit, "json-rate-limit", c.JSONRateLimit, "allowed JSON validations per second and client")
+	fs.IntVar(&c.JSONRateBurst, "json-rate-burst", c.JSONRateBurst, "maximum burst of JSON validations per client")
+	fs.Var((*networkList)(&c.TrustedProxies), "trusted-proxies", "comma separated list of proxy networks (CIDR) whose X-Forwarded-For header is trusted")
+	fs.Var((*stringList)(&c.APIKeys), "api-keys", "comma separated list of API keys which are rate limited independently of the client address")
+	fs.DurationVar(&c.FetchTimeout, "fetch-timeout", c.FetchTimeout, "timeout for fetching an endpoint")
+	fs.Int64Var(&c.MaxRequestBodySize, "max-request-body", c.MaxRequestBodySize, "maximum size of a request body in bytes")
+	fs.Int64Var(&c.MaxFetchBodySize, "max-fetch-body", c.MaxFetchBodySize, "maximum size of a fetched endpoint in bytes")
+	fs.StringVar(&c.Origin, "origin", c.Origin, "Origin header sent when fetching endpoints")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "maximum duration for reading a request")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "maximum duration for handling a request and writing the response")
+	fs.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "maximum duration a keep-alive connection stays idle")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "how long to wait for outstanding requests on shutdown")
+	fs.IntVar(&c.MaxRedirects, "max-redirects", c.MaxRedirects, "maximum number of redirects followed when fetching an endpoint")
+	fs.IntVar(&c.CertWarningDays, "cert-warning-days", c.CertWarningDays, "warn about certificates expiring within this many days")
+	fs.Var((*networkList)(&c.FetchAllow), "fetch-allow", "comma separated list of internal networks (CIDR) endpoints may be fetched from")
+	fs.StringVar(&c.SchemaDir, "schema-dir", c.SchemaDir, schemaDirUsage)
+	fs.DurationVar(&c.SchemaReload, "schema-reload", c.SchemaReload, "how often the schema directory is checked for changes, 0 disables reloading")
+	fs.IntVar(&c.JobWorkers, "job-workers", c.JobWorkers, "number of asynchronous validation jobs run in parallel")
+	fs.IntVar(&c.JobQueueSize, "job-queue", c.JobQueueSize, "maximum number of queued validation jobs, further jobs are rejected")
+	fs.DurationVar(&c.JobRetention, "job-retention", c.JobRetention, "how long the results of finished validation jobs are kept")
+	fs.IntVar(&c.BatchParallelism, "batch-parallelism", c.BatchParallelism, "number of items of a batch validated in parallel")
+	fs.IntVar(&c.BatchMaxItems, "batch-max-items", c.BatchMaxItems, "maximum number of items in a batch")
+	fs.IntVar(&c.DirectoryMaxSpaces, "directory-max-spaces", c.DirectoryMaxSpaces, "maximum number of spaces in a directory validated by /v2/validateDirectory")
+}
+
+// readFile reads a JSON object mapping flag names to values
+func readFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %v", path, err)
+	}
+
+	values := map[string]string{}
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case float64:
+			values[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case []interface{}:
+			var items []string
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[name] = strings.Join(items, ",")
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+
+	return values, nil
+}
+
+type stringList []string
+
+func (s *stringList) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = nil
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+type networkList []*net.IPNet
+
+func (n *networkList) String() string {
+	if n == nil {
+		return ""
+	}
+	var items []string
+	for _, network := range *n {
+		items = append(items, network.String())
+	}
+	return strings.Join(items, ",")
+}
+
+// Set parses a comma separated list of CIDRs, single addresses are taken as
+// a network containing only that address
+func (n *networkList) Set(value string) error {
+	*n = nil
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return fmt.Errorf("invalid address %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			*n = append(*n, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return err
+		}
+		*n = append(*n, network)
+	}
+	return nil
+}
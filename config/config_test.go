@@ -0,0 +1,184 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func load(t *testing.T, args ...string) Config {
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg := load(t)
+
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("wrong config: got %+v want %+v", cfg, Default())
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	cfg := load(t, "-listen", ":9090", "-cors-origins", "https://a.example, https://b.example", "-fetch-timeout", "3s")
+
+	if cfg.ListenAddr != ":9090" {
+		t.Errorf("wrong listen address: got %v want %v", cfg.ListenAddr, ":9090")
+	}
+
+	want := []string{"https://a.example", "https://b.example"}
+	if !reflect.DeepEqual(cfg.CORSOrigins, want) {
+		t.Errorf("wrong cors origins: got %v want %v", cfg.CORSOrigins, want)
+	}
+
+	if cfg.FetchTimeout != time.Second*3 {
+		t.Errorf("wrong fetch timeout: got %v want %v", cfg.FetchTimeout, time.Second*3)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{
+		"listen": ":1111",
+		"rate-limit": 10,
+		"max-fetch-body": 2097152,
+		"origin": "https://file.example"
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer setEnv(t, "VALIDATOR_CONFIG", path)()
+	defer setEnv(t, "VALIDATOR_RATE_LIMIT", "20")()
+	defer setEnv(t, "VALIDATOR_ORIGIN", "https://env.example")()
+
+	cfg := load(t, "-origin", "https://flag.example")
+
+	if cfg.ListenAddr != ":1111" {
+		t.Errorf("file value not applied: got %v want %v", cfg.ListenAddr, ":1111")
+	}
+	if cfg.MaxFetchBodySize != 2097152 {
+		t.Errorf("file value not applied: got %v want %v", cfg.MaxFetchBodySize, 2097152)
+	}
+	if cfg.RateLimit != 20 {
+		t.Errorf("env should override file: got %v want %v", cfg.RateLimit, 20)
+	}
+	if cfg.Origin != "https://flag.example" {
+		t.Errorf("flag should override env: got %v want %v", cfg.Origin, "https://flag.example")
+	}
+}
+
+func TestLoadSchemaDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{
+		"listen": ":1111",
+		"schema-dir": "/file/schemas"
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// server options are ignored, even invalid ones
+	defer setEnv(t, "VALIDATOR_RATE_BURST", "lots")()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	schemaDir, err := LoadSchemaDir(fs, []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schemaDir != "/file/schemas" {
+		t.Errorf("file value not applied: got %v want %v", schemaDir, "/file/schemas")
+	}
+	if fs.Lookup("listen") != nil {
+		t.Errorf("server flags were registered")
+	}
+
+	_, err = LoadSchemaDir(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-listen", ":1111"})
+	if err == nil {
+		t.Errorf("expected an error for a server flag")
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	defer setEnv(t, "VALIDATOR_RATE_BURST", "lots")()
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil {
+		t.Errorf("expected an error for an invalid value")
+	}
+}
+
+func TestLoadInvalidJobs(t *testing.T) {
+	tests := [][]string{
+		{"-job-workers", "0"},
+		{"-job-workers", "-1"},
+		{"-job-queue", "-1"},
+	}
+
+	for _, args := range tests {
+		cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), args)
+		if err != nil {
+			t.Fatalf("unexpected error for %v: %v", args, err)
+		}
+		if cfg.CheckServer() == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-job-queue", "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.CheckServer(); err != nil {
+		t.Errorf("unexpected error for an unbuffered queue: %v", err)
+	}
+}
+
+// setEnv sets an environment variable and returns a func to unset it again
+func setEnv(t *testing.T, key, value string) func() {
+	err := os.Setenv(key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		_ = os.Unsetenv(key)
+	}
+}
+
+func TestLoadFetchAllow(t *testing.T) {
+	cfg := load(t, "-fetch-allow", "10.0.0.0/8, 192.168.1.5,::1")
+
+	var got []string
+	for _, network := range cfg.FetchAllow {
+		got = append(got, network.String())
+	}
+
+	want := []string{"10.0.0.0/8", "192.168.1.5/32", "::1/128"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong networks: got %v want %v", got, want)
+	}
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-fetch-allow", "intranet"})
+	if err == nil {
+		t.Errorf("expected an error for an invalid network")
+	}
+}
@@ -1,11 +1,13 @@
 module github.com/spaceapi/validator
 
-go 1.13
+go 1.15
 
 require (
+	github.com/prometheus/client_golang v1.11.1
 	github.com/rs/cors v1.7.0
 	github.com/spaceapi-community/go-spaceapi-validator v0.2.0
 	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
+	github.com/xeipuuv/gojsonschema v1.2.0
 	goji.io v2.0.2+incompatible
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
 )
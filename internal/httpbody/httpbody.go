@@ -0,0 +1,80 @@
+// Package httpbody limits and decodes request bodies the same way for all
+// API versions
+package httpbody
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrTooLarge is returned when a body exceeds the configured size
+var ErrTooLarge = errors.New("body too large")
+
+// ErrTrailingData is returned when a JSON document is followed by more data
+var ErrTrailingData = errors.New("unexpected data after the JSON document")
+
+// limitedBody fails with ErrTooLarge once more than n bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.n -= int64(n)
+	if b.n < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// MaxBytes makes reading more than n bytes of the request body fail with
+// ErrTooLarge
+func MaxBytes(next http.Handler, n int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = &limitedBody{ReadCloser: r.Body, n: n}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DecodeStrict decodes a single JSON document and fails if anything but
+// whitespace follows it
+func DecodeStrict(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	err := decoder.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	var trailing json.RawMessage
+	err = decoder.Decode(&trailing)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrTrailingData
+}
+
+// Error responds with the given status, or with 413 if the request body
+// exceeded the size limit
+func Error(writer http.ResponseWriter, err error, status int) {
+	if err == ErrTooLarge {
+		writer.Header().Set("Connection", "close")
+		status = http.StatusRequestEntityTooLarge
+	}
+	http.Error(writer, err.Error(), status)
+}
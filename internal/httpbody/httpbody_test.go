@@ -0,0 +1,52 @@
+package httpbody
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitedBody(t *testing.T) {
+	body := &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("12345")), n: 5}
+	data, err := ioutil.ReadAll(body)
+	if err != nil || string(data) != "12345" {
+		t.Errorf("body within the limit failed: got %q, %v", data, err)
+	}
+
+	body = &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("123456")), n: 5}
+	_, err = ioutil.ReadAll(body)
+	if err != ErrTooLarge {
+		t.Errorf("wrong error: got %v want %v", err, ErrTooLarge)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	var v map[string]interface{}
+	if err := DecodeStrict(strings.NewReader(`{"url": "x"}`+"\n"), &v); err != nil {
+		t.Errorf("valid document was rejected: %v", err)
+	}
+
+	for _, document := range []string{`{"url": "x"} garbage`, `{"url": "x"}}`, `{"url": "x"}{}`} {
+		if err := DecodeStrict(strings.NewReader(document), &v); err == nil {
+			t.Errorf("trailing data was accepted: %s", document)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Error(rr, ErrTooLarge, http.StatusBadRequest)
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusRequestEntityTooLarge)
+	}
+
+	rr = httptest.NewRecorder()
+	Error(rr, ErrTrailingData, http.StatusBadRequest)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,127 @@
+// Package lint checks SpaceAPI documents for mistakes the schema can't
+// express, like swapped coordinates or stale states
+package lint
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severities of rules. Findings never make a document invalid, so there is
+// no error severity.
+const (
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Warning is a finding of a rule. Line and Column are the position of
+// Pointer, they are set by callers which know the encoded document.
+type Warning struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Pointer  string `json:"pointer,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// String formats the warning with its position, if it is known
+func (w Warning) String() string {
+	if w.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s [%s]: %s", w.Line, w.Column, w.Severity, w.Rule, w.Message)
+	}
+	return fmt.Sprintf("%s [%s]: %s", w.Severity, w.Rule, w.Message)
+}
+
+// Finding is a problem found by a rule, at the JSON pointer of the offending
+// value
+type Finding struct {
+	Message string
+	Pointer string
+}
+
+// Rule checks a document for one kind of mistake
+type Rule struct {
+	ID          string
+	Severity    string
+	Description string
+	Check       func(ctx context.Context, doc Document, env Env) []Finding
+}
+
+// Env provides what rules need besides the document
+type Env struct {
+	Now time.Time
+	// CheckLink returns an error if the resource at url can't be fetched.
+	// Rules checking links are skipped if it is nil.
+	CheckLink func(ctx context.Context, url string) error
+}
+
+// Run checks doc with every rule and returns the warnings in the order of
+// the rules
+func Run(ctx context.Context, doc Document, rules []Rule, env Env) []Warning {
+	if env.Now.IsZero() {
+		env.Now = time.Now()
+	}
+
+	var warnings []Warning
+	for _, rule := range rules {
+		for _, finding := range rule.Check(ctx, doc, env) {
+			warnings = append(warnings, Warning{
+				Rule:     rule.ID,
+				Severity: rule.Severity,
+				Message:  finding.Message,
+				Pointer:  finding.Pointer,
+			})
+		}
+	}
+	return warnings
+}
+
+// Document is a decoded SpaceAPI document
+type Document map[string]interface{}
+
+// Lookup returns the value at a JSON pointer like /location/lat
+func (d Document) Lookup(pointer string) (interface{}, bool) {
+	var value interface{} = map[string]interface{}(d)
+	if pointer == "" {
+		return value, true
+	}
+
+	for _, segment := range strings.Split(pointer, "/")[1:] {
+		segment = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Number returns the number at pointer
+func (d Document) Number(pointer string) (float64, bool) {
+	value, _ := d.Lookup(pointer)
+	number, ok := value.(float64)
+	return number, ok
+}
+
+// String returns the string at pointer
+func (d Document) String(pointer string) (string, bool) {
+	value, _ := d.Lookup(pointer)
+	s, ok := value.(string)
+	return s, ok
+}
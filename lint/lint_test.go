@@ -0,0 +1,130 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func decode(t *testing.T, document string) Document {
+	var doc Document
+	err := json.Unmarshal([]byte(document), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func rules(warnings []Warning) []string {
+	var ids []string
+	for _, warning := range warnings {
+		ids = append(ids, warning.Rule+" "+warning.Pointer)
+	}
+	return ids
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{
+			"clean",
+			`{"location": {"lat": 48.77, "lon": 9.23}, "state": {"lastchange": 1717200000},
+			  "issue_report_channels": ["email"], "contact": {"email": "info@example.com"}}`,
+			nil,
+		},
+		{
+			"swapped",
+			`{"location": {"lat": 148.77, "lon": 9.23}}`,
+			[]string{"location-swapped /location/lat"},
+		},
+		{
+			"future",
+			`{"state": {"lastchange": 1717200000000}}`,
+			[]string{"lastchange-future /state/lastchange"},
+		},
+		{
+			"stale",
+			`{"state": {"lastchange": 1500000000}}`,
+			[]string{"lastchange-stale /state/lastchange"},
+		},
+		{
+			"channels",
+			`{"issue_report_channels": ["email", "twitter", "ml"], "contact": {"email": "info@example.com", "twitter": ""}}`,
+			[]string{"issue-channel-missing /issue_report_channels/1", "issue-channel-missing /issue_report_channels/2"},
+		},
+	}
+
+	for _, test := range tests {
+		warnings := Run(context.Background(), decode(t, test.document), DefaultRules, Env{Now: now})
+		got := rules(warnings)
+		if len(got) != len(test.want) {
+			t.Errorf("%s: wrong warnings: got %v want %v", test.name, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%s: wrong warning: got %v want %v", test.name, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestRunSeverity(t *testing.T) {
+	warnings := Run(context.Background(), decode(t, `{"issue_report_channels": ["email"]}`), DefaultRules, Env{Now: now})
+
+	if len(warnings) != 1 || warnings[0].Severity != SeverityWarning {
+		t.Errorf("wrong warnings: %+v", warnings)
+	}
+
+	for _, rule := range DefaultRules {
+		if rule.Severity != SeverityWarning && rule.Severity != SeverityInfo {
+			t.Errorf("rule %s has severity %q", rule.ID, rule.Severity)
+		}
+	}
+}
+
+func TestLogoRule(t *testing.T) {
+	doc := decode(t, `{"logo": "https://example.com/logo.png"}`)
+
+	if warnings := Run(context.Background(), doc, DefaultRules, Env{Now: now}); len(warnings) != 0 {
+		t.Errorf("logo checked without CheckLink: %+v", warnings)
+	}
+
+	var checked string
+	env := Env{Now: now, CheckLink: func(_ context.Context, url string) error {
+		checked = url
+		return errors.New("status 404 Not Found")
+	}}
+	warnings := Run(context.Background(), doc, DefaultRules, env)
+
+	if checked != "https://example.com/logo.png" {
+		t.Errorf("wrong link checked: %v", checked)
+	}
+	if got := rules(warnings); len(got) != 1 || got[0] != "logo-unreachable /logo" {
+		t.Errorf("wrong warnings: %v", got)
+	}
+}
+
+func TestDocumentLookup(t *testing.T) {
+	doc := decode(t, `{"a": {"b/c": [1, {"d~e": "x"}]}}`)
+
+	if value, ok := doc.String("/a/b~1c/1/d~0e"); !ok || value != "x" {
+		t.Errorf("wrong value: %v %v", value, ok)
+	}
+	if value, ok := doc.Number("/a/b~1c/0"); !ok || value != 1 {
+		t.Errorf("wrong value: %v %v", value, ok)
+	}
+	if _, ok := doc.Lookup("/a/b~1c/2"); ok {
+		t.Errorf("found value out of range")
+	}
+	if _, ok := doc.Lookup("/a/missing"); ok {
+		t.Errorf("found missing value")
+	}
+}
@@ -0,0 +1,148 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// clockSkew is tolerated before a lastchange is considered in the future
+const clockSkew = time.Minute * 5
+
+// staleAfter is the age of a lastchange after which the state is considered
+// not to be maintained anymore
+const staleAfter = time.Hour * 24 * 365
+
+// DefaultRules are the rules documents are checked with
+var DefaultRules = []Rule{
+	{
+		ID:       "location-swapped",
+		Severity: SeverityWarning,
+		// a swap is only noticed if it makes the latitude invalid, e.g.
+		// not for lat 9 and lon 48 in Europe
+		Description: "lat is out of range while lon would be a valid latitude, so they seem to be swapped",
+		Check:       checkLocation,
+	},
+	{
+		ID:          "lastchange-future",
+		Severity:    SeverityWarning,
+		Description: "state.lastchange is in the future",
+		Check:       checkLastchangeFuture,
+	},
+	{
+		ID:          "lastchange-stale",
+		Severity:    SeverityInfo,
+		Description: "state.lastchange is more than a year old",
+		Check:       checkLastchangeStale,
+	},
+	{
+		ID:          "issue-channel-missing",
+		Severity:    SeverityWarning,
+		Description: "issue_report_channels names a contact field which isn't set",
+		Check:       checkIssueChannels,
+	},
+	{
+		ID:          "logo-unreachable",
+		Severity:    SeverityWarning,
+		Description: "logo can't be fetched, only checked if Env.CheckLink is set",
+		Check:       checkLogo,
+	},
+}
+
+func checkLocation(_ context.Context, doc Document, _ Env) []Finding {
+	lat, okLat := doc.Number("/location/lat")
+	lon, okLon := doc.Number("/location/lon")
+	if !okLat || !okLon {
+		return nil
+	}
+
+	if math.Abs(lat) > 90 && math.Abs(lon) <= 90 {
+		return []Finding{{
+			Message: fmt.Sprintf("lat %v is out of range while lon %v would be a valid latitude", lat, lon),
+			Pointer: "/location/lat",
+		}}
+	}
+	return nil
+}
+
+func lastchange(doc Document) (time.Time, bool) {
+	value, ok := doc.Number("/state/lastchange")
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
+
+func checkLastchangeFuture(_ context.Context, doc Document, env Env) []Finding {
+	changed, ok := lastchange(doc)
+	if !ok || !changed.After(env.Now.Add(clockSkew)) {
+		return nil
+	}
+	return []Finding{{
+		Message: fmt.Sprintf("lastchange %s is in the future, it must be a unix timestamp in seconds", changed.UTC().Format(time.RFC3339)),
+		Pointer: "/state/lastchange",
+	}}
+}
+
+func checkLastchangeStale(_ context.Context, doc Document, env Env) []Finding {
+	changed, ok := lastchange(doc)
+	if !ok || env.Now.Sub(changed) <= staleAfter {
+		return nil
+	}
+	return []Finding{{
+		Message: fmt.Sprintf("lastchange %s is more than a year ago, is the state still updated?", changed.UTC().Format(time.RFC3339)),
+		Pointer: "/state/lastchange",
+	}}
+}
+
+func checkIssueChannels(_ context.Context, doc Document, _ Env) []Finding {
+	value, _ := doc.Lookup("/issue_report_channels")
+	channels, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var findings []Finding
+	for i, channel := range channels {
+		name, ok := channel.(string)
+		if !ok {
+			continue
+		}
+		if contact, _ := doc.Lookup("/contact/" + name); isEmpty(contact) {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("issue report channel %q is not set in contact", name),
+				Pointer: "/issue_report_channels/" + strconv.Itoa(i),
+			})
+		}
+	}
+	return findings
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func checkLogo(ctx context.Context, doc Document, env Env) []Finding {
+	logo, ok := doc.String("/logo")
+	if !ok || logo == "" || env.CheckLink == nil {
+		return nil
+	}
+
+	if err := env.CheckLink(ctx, logo); err != nil {
+		return []Finding{{
+			Message: fmt.Sprintf("logo %s can't be fetched: %v", logo, err),
+			Pointer: "/logo",
+		}}
+	}
+	return nil
+}
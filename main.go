@@ -1,18 +1,40 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"github.com/rs/cors"
+	"github.com/spaceapi/validator/config"
+	"github.com/spaceapi/validator/schema"
 	"github.com/spaceapi/validator/v1"
 	"github.com/spaceapi/validator/v2"
 	"goji.io"
 	"goji.io/pat"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
+	flag.Usage = usage
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = cfg.CheckServer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	c := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
+		AllowedOrigins: cfg.CORSOrigins,
 	})
 
 	root := goji.NewMux()
@@ -28,11 +50,53 @@ func main() {
 		http.Redirect(writer, request, "/v2/", 302)
 	})
 
-	root.Handle(pat.New("/v1/*"), v1.GetSubMux())
-	root.Handle(pat.New("/v2/*"), v2.GetSubMux())
+	schemas, err := schema.NewRegistry(cfg.SchemaDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	base, abort := context.WithCancel(context.Background())
+	defer abort()
+
+	v2Mux, stopJobs := v2.GetSubMux(base, cfg, schemas)
+	root.Handle(pat.New("/v1/*"), v1.GetSubMux(cfg))
+	root.Handle(pat.New("/v2/*"), v2Mux)
+
+	go schemas.Watch(base, cfg.SchemaReload)
+
+	requests := &inFlight{}
+	srv := newServer(cfg, root, requests, base)
+
+	ln, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.MetricsListenAddr != "" {
+		metricsLn, err := net.Listen("tcp", cfg.MetricsListenAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		metricsSrv := newMetricsServer(cfg)
+		defer metricsSrv.Close()
+
+		log.Printf("serving metrics on %s...", cfg.MetricsListenAddr)
+		go func() {
+			err := metricsSrv.Serve(metricsLn)
+			if err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
-	log.Println("starting validator on port 8080...")
-	log.Fatal(http.ListenAndServe(":8080", root))
+	log.Printf("starting validator on %s...", cfg.ListenAddr)
+	err = serve(srv, ln, stop, cfg.ShutdownTimeout, requests, stopJobs, abort)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func versionRedirect(writer http.ResponseWriter, request *http.Request) {
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/spaceapi/validator/config"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestRootRedirect(t *testing.T) {
@@ -49,3 +55,180 @@ func TestOpenApi(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMetricsServer(t *testing.T) {
+	handler := newMetricsServer(config.Default()).Handler
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	req, err = http.NewRequest("GET", "/v2/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
+
+func TestServeDrainsRequests(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	base, abort := context.WithCancel(context.Background())
+	defer abort()
+	requests := &inFlight{}
+	srv := newServer(config.Default(), handler, requests, base)
+
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(srv, ln, stop, time.Second*5, requests, func() {}, abort)
+	}()
+
+	responses := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			responses <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		responses <- resp.StatusCode
+	}()
+
+	<-started
+	stop <- syscall.SIGTERM
+
+	if count := requests.count(); count != 1 {
+		t.Errorf("wrong number of outstanding requests: got %v want %v", count, 1)
+	}
+	close(release)
+
+	if status := <-responses; status != http.StatusOK {
+		t.Errorf("outstanding request was not drained: got %v want %v", status, http.StatusOK)
+	}
+
+	if err := <-served; err != nil {
+		t.Errorf("serve returned an error: %v", err)
+	}
+}
+
+func TestServeAbortsAfterTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	base, abort := context.WithCancel(context.Background())
+	defer abort()
+	requests := &inFlight{}
+	srv := newServer(config.Default(), handler, requests, base)
+
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(srv, ln, stop, time.Millisecond*100, requests, func() {}, abort)
+	}()
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	<-started
+	stop <- syscall.SIGINT
+
+	if err := <-served; err != context.DeadlineExceeded {
+		t.Errorf("wrong error: got %v want %v", err, context.DeadlineExceeded)
+	}
+
+	if base.Err() == nil {
+		t.Errorf("outstanding requests were not aborted")
+	}
+}
+
+func TestServeWaitsForJobs(t *testing.T) {
+	tests := map[string]struct {
+		timeout time.Duration
+		want    error
+	}{
+		"drained": {time.Second * 5, nil},
+		"aborted": {time.Millisecond * 100, context.DeadlineExceeded},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			base, abort := context.WithCancel(context.Background())
+			defer abort()
+			requests := &inFlight{}
+			srv := newServer(config.Default(), http.NotFoundHandler(), requests, base)
+
+			// the job finishes after a while or once it is aborted
+			stopped := make(chan struct{})
+			stopJobs := func() {
+				select {
+				case <-time.After(time.Millisecond * 500):
+				case <-base.Done():
+				}
+				close(stopped)
+			}
+
+			stop := make(chan os.Signal, 1)
+			served := make(chan error, 1)
+			go func() {
+				served <- serve(srv, ln, stop, test.timeout, requests, stopJobs, abort)
+			}()
+			stop <- syscall.SIGINT
+
+			if err := <-served; err != test.want {
+				t.Errorf("wrong error: got %v want %v", err, test.want)
+			}
+			if test.want == nil {
+				select {
+				case <-stopped:
+				default:
+					t.Errorf("serve returned before the jobs were done")
+				}
+			} else if base.Err() == nil {
+				t.Errorf("running jobs were not aborted")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package metrics exposes Prometheus metrics about the validator
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "spaceapi_validator"
+
+// Route labels of the instrumented endpoints
+const (
+	RouteV1Validate     = "v1_validate"
+	RouteV2ValidateJSON = "v2_validateJSON"
+	RouteV2ValidateURL  = "v2_validateURL"
+	RouteV2Migrate      = "v2_migrate"
+	RouteV2Jobs         = "v2_jobs"
+	RouteV2Job          = "v2_job"
+	RouteV2Batch        = "v2_validateBatch"
+	RouteV2Directory    = "v2_validateDirectory"
+)
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of handled requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of handled requests by route and method.",
+		Buckets:   []float64{.005, .01, .05, .1, .5, 1, 2.5, 5, 10, 20},
+	}, []string{"route", "method"})
+
+	validations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validations_total",
+		Help:      "Number of validated documents by route and result (valid or invalid).",
+	}, []string{"route", "result"})
+
+	checkFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "check_failures_total",
+		Help:      "Number of failed endpoint checks by check.",
+	}, []string{"check"})
+
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by the rate limiter by route.",
+	}, []string{"route"})
+
+	queuedJobs = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queued_jobs",
+		Help:      "Number of validation jobs waiting for a worker.",
+	})
+
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_duration_seconds",
+		Help:      "Duration of fetching endpoints by result (ok or error).",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"result"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Instrument counts the requests handled by next and measures their duration
+func Instrument(route string, next http.Handler) http.Handler {
+	labels := prometheus.Labels{"route": route}
+	return promhttp.InstrumentHandlerDuration(
+		requestDuration.MustCurryWith(labels),
+		promhttp.InstrumentHandlerCounter(requests.MustCurryWith(labels), next),
+	)
+}
+
+// Validation records the result of validating a document
+func Validation(route string, valid bool) {
+	result := "invalid"
+	if valid {
+		result = "valid"
+	}
+	validations.WithLabelValues(route, result).Inc()
+}
+
+// CheckFailed records a failed endpoint check, e.g. cors or certValid
+func CheckFailed(check string) {
+	checkFailures.WithLabelValues(check).Inc()
+}
+
+// RateLimited records a request rejected by the rate limiter
+func RateLimited(route string) {
+	rateLimited.WithLabelValues(route).Inc()
+}
+
+// Fetch records the duration of fetching an endpoint
+func Fetch(seconds float64, ok bool) {
+	result := "error"
+	if ok {
+		result = "ok"
+	}
+	fetchDuration.WithLabelValues(result).Observe(seconds)
+}
+
+// QueuedJobs records the number of validation jobs waiting for a worker
+func QueuedJobs(n int) {
+	queuedJobs.Set(float64(n))
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	handler := Instrument("test", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	count := testutil.ToFloat64(requests.WithLabelValues("test", "post", "418"))
+	if count != 2 {
+		t.Errorf("wrong request count: got %v want %v", count, 2)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	Validation("test", true)
+	Validation("test", false)
+	Validation("test", false)
+
+	if count := testutil.ToFloat64(validations.WithLabelValues("test", "valid")); count != 1 {
+		t.Errorf("wrong valid count: got %v want %v", count, 1)
+	}
+	if count := testutil.ToFloat64(validations.WithLabelValues("test", "invalid")); count != 2 {
+		t.Errorf("wrong invalid count: got %v want %v", count, 2)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	CheckFailed("cors")
+	RateLimited("test")
+	Fetch(0.2, true)
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, req)
+
+	for _, metric := range []string{
+		`spaceapi_validator_check_failures_total{check="cors"} 1`,
+		`spaceapi_validator_rate_limited_total{route="test"} 1`,
+		`spaceapi_validator_fetch_duration_seconds_count{result="ok"} 1`,
+	} {
+		if !strings.Contains(rr.Body.String(), metric) {
+			t.Errorf("metric missing: %s", metric)
+		}
+	}
+}
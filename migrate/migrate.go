@@ -0,0 +1,356 @@
+// Package migrate rewrites SpaceAPI documents for newer schema versions
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operations of changes, named like the operations of JSON patches
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+)
+
+// Change is a modification made to a document
+type Change struct {
+	Op      string `json:"op"`
+	Pointer string `json:"pointer"`
+	// From is the former location of a moved or converted value
+	From    string `json:"from,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the change like "move /contact/xmpp: message"
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Op, c.Pointer, c.Message)
+}
+
+// Todo is something which can't be migrated automatically and needs to be
+// done by hand
+type Todo struct {
+	Pointer string `json:"pointer,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the todo like "/state/open: message"
+func (t Todo) String() string {
+	if t.Pointer == "" {
+		return t.Message
+	}
+	return t.Pointer + ": " + t.Message
+}
+
+// Result is a migrated document and what was done to it
+type Result struct {
+	From     string
+	To       string
+	Document map[string]interface{}
+	Changes  []Change
+	Todos    []Todo
+}
+
+// step migrates a document from one version to the next
+type step struct {
+	from, to int
+	apply    func(m *migration)
+}
+
+var steps = []step{
+	{from: 13, to: 14, apply: to14},
+	{from: 14, to: 15, apply: to15},
+	{from: 15, to: 16, apply: to16},
+}
+
+// Migrate rewrites document for the target version. The version the document
+// is migrated from is the newest one it declares.
+func Migrate(document []byte, target string) (*Result, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("document is not a JSON object")
+	}
+
+	from, err := declaredVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+	to, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(target), "0."))
+	if err != nil {
+		return nil, fmt.Errorf("invalid target version %q", target)
+	}
+
+	switch {
+	case from < steps[0].from:
+		return nil, fmt.Errorf("migrating from v%d isn't supported, only from v%d or newer", from, steps[0].from)
+	case to <= steps[0].from:
+		return nil, fmt.Errorf("migrating to v%d isn't supported, only to v%d or newer", to, steps[0].to)
+	case to > steps[len(steps)-1].to:
+		return nil, fmt.Errorf("migrating to v%d isn't supported yet", to)
+	case from > to:
+		return nil, fmt.Errorf("document declares v%d, which is newer than v%d", from, to)
+	}
+
+	m := &migration{doc: doc}
+	m.setCompatibility(strconv.Itoa(to))
+	for _, s := range steps {
+		if s.from >= from && s.to <= to {
+			s.apply(m)
+		}
+	}
+
+	return &Result{
+		From:     strconv.Itoa(from),
+		To:       strconv.Itoa(to),
+		Document: doc,
+		Changes:  m.changes,
+		Todos:    m.todos,
+	}, nil
+}
+
+// declaredVersion returns the newest version a document declares in api or
+// api_compatibility
+func declaredVersion(doc map[string]interface{}) (int, error) {
+	var declared []string
+	if api, ok := doc["api"]; ok {
+		declared = append(declared, fmt.Sprintf("%v", api))
+	}
+	if list, ok := doc["api_compatibility"].([]interface{}); ok {
+		for _, version := range list {
+			declared = append(declared, fmt.Sprintf("%v", version))
+		}
+	}
+
+	newest := -1
+	for _, version := range declared {
+		v, err := strconv.Atoi(strings.TrimPrefix(version, "0."))
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q", version)
+		}
+		if v > newest {
+			newest = v
+		}
+	}
+	if newest < 0 {
+		return 0, fmt.Errorf("document declares no version in api or api_compatibility")
+	}
+	return newest, nil
+}
+
+// migration is the state of a document while it is migrated
+type migration struct {
+	doc     map[string]interface{}
+	changes []Change
+	todos   []Todo
+}
+
+func (m *migration) change(op, pointer, from, message string) {
+	m.changes = append(m.changes, Change{Op: op, Pointer: pointer, From: from, Message: message})
+}
+
+func (m *migration) todo(pointer, message string) {
+	m.todos = append(m.todos, Todo{Pointer: pointer, Message: message})
+}
+
+// object returns the object at a pointer, if there is one
+func (m *migration) object(pointer string) (map[string]interface{}, bool) {
+	value := map[string]interface{}(m.doc)
+	for _, key := range segments(pointer) {
+		next, ok := value[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value = next
+	}
+	return value, true
+}
+
+// lookup returns the value at a pointer to a member of an object
+func (m *migration) lookup(pointer string) (interface{}, bool) {
+	parent, key := split(pointer)
+	object, ok := m.object(parent)
+	if !ok {
+		return nil, false
+	}
+	value, ok := object[key]
+	return value, ok
+}
+
+// remove removes the member at pointer, if it exists
+func (m *migration) remove(pointer, message string) {
+	parent, key := split(pointer)
+	object, ok := m.object(parent)
+	if !ok {
+		return
+	}
+	if _, ok := object[key]; !ok {
+		return
+	}
+	delete(object, key)
+	m.change(OpRemove, pointer, "", message)
+}
+
+// move renames the member at from to the one at to, which must be in the same
+// object. Nothing is moved if to is already set.
+func (m *migration) move(from, to, message string) {
+	parent, key := split(from)
+	object, ok := m.object(parent)
+	if !ok {
+		return
+	}
+	value, ok := object[key]
+	if !ok {
+		return
+	}
+
+	_, newKey := split(to)
+	if _, exists := object[newKey]; exists {
+		m.todo(from, fmt.Sprintf("%s and %s are both set, merge them into %s", from, to, to))
+		return
+	}
+	delete(object, key)
+	object[newKey] = value
+	m.change(OpMove, to, from, message)
+}
+
+// addLink appends an entry to links, which were introduced in v14
+func (m *migration) addLink(from, name, url, description string) {
+	links, _ := m.doc["links"].([]interface{})
+	link := map[string]interface{}{"name": name, "url": url}
+	if description != "" {
+		link["description"] = description
+	}
+	m.doc["links"] = append(links, link)
+	m.change(OpAdd, "/links/"+strconv.Itoa(len(links)), from, fmt.Sprintf("%s is listed as link %q", from, name))
+}
+
+// setCompatibility replaces api and api_compatibility by the target version,
+// unless the document already declares it in api_compatibility. A leftover
+// api is removed in either case, as it is not allowed next to
+// api_compatibility anymore.
+func (m *migration) setCompatibility(target string) {
+	if list, ok := m.doc["api_compatibility"].([]interface{}); ok {
+		for _, version := range list {
+			if fmt.Sprintf("%v", version) != target {
+				continue
+			}
+			if api, ok := m.doc["api"]; ok {
+				delete(m.doc, "api")
+				m.change(OpRemove, "/api", "", fmt.Sprintf("api %q is removed, api_compatibility already contains %q", fmt.Sprintf("%v", api), target))
+			}
+			return
+		}
+	}
+
+	if api, ok := m.doc["api"]; ok {
+		delete(m.doc, "api")
+		m.doc["api_compatibility"] = []interface{}{target}
+		m.change(OpMove, "/api_compatibility", "/api", fmt.Sprintf("api %q is replaced by api_compatibility [%q]", fmt.Sprintf("%v", api), target))
+		return
+	}
+
+	op := OpAdd
+	if _, ok := m.doc["api_compatibility"]; ok {
+		op = OpReplace
+	}
+	m.doc["api_compatibility"] = []interface{}{target}
+	m.change(op, "/api_compatibility", "", fmt.Sprintf("api_compatibility is set to [%q]", target))
+}
+
+func to14(m *migration) {
+	m.move("/contact/jabber", "/contact/xmpp", "contact.jabber was renamed to contact.xmpp")
+	m.remove("/contact/google", "contact.google was removed, Google+ was shut down")
+	m.remove("/cache", "cache was removed")
+	m.remove("/spacefed/spacephone", "spacefed.spacephone was removed")
+	m.remove("/issue_report_channels", "issue_report_channels was removed")
+
+	if shows, ok := m.doc["radio_show"].([]interface{}); ok {
+		for i, show := range shows {
+			from := "/radio_show/" + strconv.Itoa(i)
+			entry, _ := show.(map[string]interface{})
+			name, _ := entry["name"].(string)
+			url, _ := entry["url"].(string)
+			if name == "" || url == "" {
+				m.todo(from, "radio show without name or url can't be converted into a link")
+				continue
+			}
+			m.addLink(from, name, url, radioShowDescription(entry))
+		}
+		m.remove("/radio_show", "radio_show was removed, the shows are listed in links")
+	}
+
+	if streams, ok := m.doc["stream"].(map[string]interface{}); ok {
+		var names []string
+		for name := range streams {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			from := "/stream/" + escape(name)
+			url, ok := streams[name].(string)
+			if !ok || url == "" {
+				m.todo(from, "stream without url can't be converted into a link")
+				continue
+			}
+			m.addLink(from, strings.TrimPrefix(name, "ext_")+" stream", url, "")
+		}
+		m.remove("/stream", "stream was removed, the streams are listed in links")
+	}
+}
+
+func radioShowDescription(show map[string]interface{}) string {
+	var parts []string
+	if kind, ok := show["type"].(string); ok && kind != "" {
+		parts = append(parts, kind+" radio show")
+	}
+	start, _ := show["start"].(string)
+	end, _ := show["end"].(string)
+	if start != "" && end != "" {
+		parts = append(parts, "from "+start+" to "+end)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func to15(m *migration) {
+	if open, ok := m.lookup("/state/open"); ok && open == nil {
+		m.remove("/state/open", "state.open can't be null anymore, an unknown state is expressed by omitting it")
+	}
+}
+
+func to16(m *migration) {
+	if _, ok := m.lookup("/sensors/carbondioxide"); ok {
+		m.todo("/sensors/carbondioxide", "sensors.carbondioxide isn't part of v16, remove it or rename it to ext_carbondioxide")
+	}
+}
+
+func split(pointer string) (string, string) {
+	i := strings.LastIndex(pointer, "/")
+	return pointer[:i], unescape(pointer[i+1:])
+}
+
+func segments(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	var keys []string
+	for _, segment := range strings.Split(pointer, "/")[1:] {
+		keys = append(keys, unescape(segment))
+	}
+	return keys
+}
+
+func escape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+func unescape(segment string) string {
+	return strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+}
@@ -0,0 +1,173 @@
+package migrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var space13 = `{
+	"api": "0.13",
+	"space": "my cool space",
+	"logo": "https://example.com/logo.png",
+	"url": "https://example.com",
+	"location": {
+		"lon": 9.236,
+		"lat": 48.777
+	},
+	"state": {
+		"open": null
+	},
+	"contact": {
+		"jabber": "space@jabber.example.com",
+		"google": {"plus": "https://plus.google.com/+space"}
+	},
+	"cache": {"schedule": "m.02"},
+	"issue_report_channels": ["email"],
+	"radio_show": [{
+		"name": "space radio",
+		"url": "https://radio.example.com/",
+		"type": "mp3",
+		"start": "2019-01-01T20:00Z",
+		"end": "2019-01-01T22:00Z"
+	}],
+	"stream": {
+		"mjpeg": "https://example.com/cam.mjpeg"
+	}
+}`
+
+func ops(changes []Change) []string {
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.Op+" "+change.Pointer)
+	}
+	return got
+}
+
+func TestMigrate13To14(t *testing.T) {
+	result, err := Migrate([]byte(space13), "14")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.From != "13" || result.To != "14" {
+		t.Errorf("wrong versions: got %v to %v", result.From, result.To)
+	}
+
+	want := []string{
+		"move /api_compatibility",
+		"move /contact/xmpp",
+		"remove /contact/google",
+		"remove /cache",
+		"remove /issue_report_channels",
+		"add /links/0",
+		"remove /radio_show",
+		"add /links/1",
+		"remove /stream",
+	}
+	if got := ops(result.Changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong changes:\ngot  %v\nwant %v", got, want)
+	}
+
+	doc := result.Document
+	if _, ok := doc["api"]; ok {
+		t.Error("api was not removed")
+	}
+	if got := doc["api_compatibility"]; !reflect.DeepEqual(got, []interface{}{"14"}) {
+		t.Errorf("wrong api_compatibility: %v", got)
+	}
+	if got := doc["contact"].(map[string]interface{})["xmpp"]; got != "space@jabber.example.com" {
+		t.Errorf("jabber was not moved to xmpp: %v", got)
+	}
+
+	links := doc["links"].([]interface{})
+	show := links[0].(map[string]interface{})
+	if show["name"] != "space radio" || !strings.Contains(show["description"].(string), "2019-01-01T20:00Z") {
+		t.Errorf("radio show was not converted: %v", show)
+	}
+	if stream := links[1].(map[string]interface{}); stream["url"] != "https://example.com/cam.mjpeg" {
+		t.Errorf("stream was not converted: %v", stream)
+	}
+
+	if _, ok := doc["state"].(map[string]interface{})["open"]; !ok {
+		t.Error("state.open was removed although v14 allows null")
+	}
+}
+
+func TestMigrate13To15(t *testing.T) {
+	result, err := Migrate([]byte(space13), "0.15")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := result.Document["state"].(map[string]interface{})["open"]; ok {
+		t.Error("state.open null was not removed")
+	}
+	if got := result.Document["api_compatibility"]; !reflect.DeepEqual(got, []interface{}{"15"}) {
+		t.Errorf("wrong api_compatibility: %v", got)
+	}
+}
+
+func TestMigrateConflict(t *testing.T) {
+	result, err := Migrate([]byte(`{"api": "0.13", "contact": {"jabber": "a@example.com", "xmpp": "b@example.com"}}`), "14")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contact := result.Document["contact"].(map[string]interface{})
+	if contact["jabber"] != "a@example.com" || contact["xmpp"] != "b@example.com" {
+		t.Errorf("conflicting fields were changed: %v", contact)
+	}
+	if len(result.Todos) != 1 || result.Todos[0].Pointer != "/contact/jabber" {
+		t.Errorf("conflict was not reported: %v", result.Todos)
+	}
+}
+
+func TestMigrateUpToDate(t *testing.T) {
+	result, err := Migrate([]byte(`{"api_compatibility": ["14", "15"], "state": {"open": null}}`), "15")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.From != "15" || len(result.Changes) != 0 {
+		t.Errorf("document declaring the target was changed: %v %v", result.From, result.Changes)
+	}
+}
+
+func TestMigrateApiAndCompatibility(t *testing.T) {
+	result, err := Migrate([]byte(`{"api": "0.13", "api_compatibility": ["14"]}`), "14")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := result.Document["api"]; ok {
+		t.Error("api was not removed")
+	}
+	if got := result.Document["api_compatibility"]; !reflect.DeepEqual(got, []interface{}{"14"}) {
+		t.Errorf("wrong api_compatibility: %v", got)
+	}
+	if got := ops(result.Changes); !reflect.DeepEqual(got, []string{"remove /api"}) {
+		t.Errorf("wrong changes: %v", got)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	tests := map[string]struct {
+		document string
+		target   string
+	}{
+		"not an object":   {`[]`, "14"},
+		"no version":      {`{"space": "my cool space"}`, "14"},
+		"too old":         {`{"api": "0.12"}`, "14"},
+		"target too old":  {`{"api": "0.13"}`, "13"},
+		"unknown target":  {`{"api": "0.13"}`, "99"},
+		"invalid target":  {`{"api": "0.13"}`, "latest"},
+		"newer than goal": {`{"api_compatibility": ["15"]}`, "14"},
+	}
+
+	for name, test := range tests {
+		if _, err := Migrate([]byte(test.document), test.target); err == nil {
+			t.Errorf("%s: no error returned", name)
+		}
+	}
+}
@@ -65,6 +65,12 @@ var openapi = `{
           "400": {
             "description": "request body is malformed"
           },
+          "413": {
+            "description": "request body exceeds the maximum size"
+          },
+          "429": {
+            "description": "rate limit exceeded, retry after the number of seconds in the Retry-After header"
+          },
           "500": {
             "description": "something went wrong"
           }
@@ -120,8 +126,17 @@ var openapi = `{
           "400": {
             "description": "request body is malformed"
           },
+          "413": {
+            "description": "request body exceeds the maximum size"
+          },
+          "429": {
+            "description": "rate limit exceeded, retry after the number of seconds in the Retry-After header"
+          },
           "500": {
             "description": "something went wrong"
+          },
+          "504": {
+            "description": "the endpoint didn't respond in time, validate it with /v2/jobs instead"
           }
         }
       }
@@ -132,6 +147,40 @@ var openapi = `{
           "v2"
         ],
         "summary": "validate an input against the SpaceApi schema",
+        "parameters": [
+          {
+            "name": "format",
+            "in": "query",
+            "description": "output format, overrides the Accept header",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "json",
+                "junit",
+                "sarif",
+                "github"
+              ]
+            }
+          },
+          {
+            "name": "file",
+            "in": "query",
+            "description": "file name used in junit, sarif and github output",
+            "schema": {
+              "type": "string",
+              "default": "spaceapi.json"
+            }
+          },
+          {
+            "name": "versions",
+            "in": "query",
+            "description": "comma separated SpaceAPI versions to validate against instead of the ones the document declares",
+            "schema": {
+              "type": "string",
+              "example": "14,15"
+            }
+          }
+        ],
         "requestBody": {
           "required": true,
           "content": {
@@ -150,21 +199,359 @@ var openapi = `{
                 "schema": {
                   "$ref": "#/components/schemas/ValidateJsonV2Response"
                 }
+              },
+              "application/xml": {
+                "schema": {
+                  "description": "JUnit XML report",
+                  "type": "string"
+                }
+              },
+              "application/sarif+json": {
+                "schema": {
+                  "description": "SARIF 2.1.0 log",
+                  "type": "object"
+                }
+              },
+              "text/plain": {
+                "schema": {
+                  "description": "GitHub Actions error annotations",
+                  "type": "string"
+                }
               }
             }
           },
           "400": {
             "description": "request body is malformed"
           },
+          "413": {
+            "description": "request body exceeds the maximum size"
+          },
+          "429": {
+            "description": "rate limit exceeded, retry after the number of seconds in the Retry-After header"
+          },
+          "500": {
+            "description": "something went wrong"
+          }
+        }
+      }
+    },
+    "/v2/migrate": {
+      "post": {
+        "tags": [
+          "v2"
+        ],
+        "summary": "migrate a document to a newer SpaceAPI version",
+        "parameters": [
+          {
+            "name": "version",
+            "in": "query",
+            "description": "SpaceAPI version to migrate to, defaults to the newest stable version",
+            "schema": {
+              "type": "string",
+              "example": "15"
+            }
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ValidateJsonV2"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "successful operation",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/MigrateV2Response"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "request body is malformed, or the document or version can't be migrated"
+          },
+          "413": {
+            "description": "request body exceeds the maximum size"
+          },
+          "429": {
+            "description": "rate limit exceeded, retry after the number of seconds in the Retry-After header"
+          },
           "500": {
             "description": "something went wrong"
           }
         }
       }
+    },
+    "/v2/validateBatch": {
+      "post": {
+        "tags": [
+          "v2"
+        ],
+        "summary": "validate several URLs and documents",
+        "parameters": [
+          {
+            "name": "format",
+            "in": "query",
+            "description": "ndjson streams the results as they are done, overrides the Accept header",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "json",
+                "ndjson"
+              ]
+            }
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ValidateBatchV2"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "successful operation",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ValidateBatchV2Response"
+                }
+              },
+              "application/x-ndjson": {
+                "schema": {
+                  "$ref": "#/components/schemas/BatchResult"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "request body is malformed, has too many items or more urls than can be fetched before the write timeout"
+          },
+          "413": {
+            "description": "request body exceeds the maximum size"
+          },
+          "429": {
+            "description": "rate limit exceeded, every url item counts as one request, retry after the number of seconds in the Retry-After header"
+          }
+        }
+      }
+    },
+    "/v2/validateDirectory": {
+      "post": {
+        "tags": [
+          "v2"
+        ],
+        "summary": "validate the endpoints listed in a SpaceAPI directory",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ValidateDirectoryV2"
+              }
+            }
+          }
+        },
+        "responses": {
+          "202": {
+            "description": "job queued, its URL is returned in the Location header and its directory property holds the report once it is done",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Job"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "request body is malformed or the directory has too many spaces"
+          },
+          "413": {
+            "description": "request body exceeds the maximum size"
+          },
+          "429": {
+            "description": "rate limit exceeded, every listed endpoint counts as one request, retry after the number of seconds in the Retry-After header"
+          },
+          "502": {
+            "description": "directory can't be fetched"
+          },
+          "503": {
+            "description": "too many queued jobs, retry after the number of seconds in the Retry-After header"
+          }
+        }
+      }
+    },
+    "/v2/jobs": {
+      "post": {
+        "tags": [
+          "v2"
+        ],
+        "summary": "validate an URL asynchronously",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/JobV2Request"
+              }
+            }
+          }
+        },
+        "responses": {
+          "202": {
+            "description": "job queued, its URL is returned in the Location header",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Job"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "request body is malformed"
+          },
+          "413": {
+            "description": "request body exceeds the maximum size"
+          },
+          "429": {
+            "description": "rate limit exceeded, retry after the number of seconds in the Retry-After header"
+          },
+          "503": {
+            "description": "too many queued jobs, retry after the number of seconds in the Retry-After header"
+          }
+        }
+      }
+    },
+    "/v2/jobs/{id}": {
+      "get": {
+        "tags": [
+          "v2"
+        ],
+        "summary": "get the status and result of a validation job",
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "successful operation",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Job"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "unknown or expired job"
+          },
+          "429": {
+            "description": "rate limit exceeded, retry after the number of seconds in the Retry-After header"
+          }
+        }
+      }
+    },
+    "/v2/schemas": {
+      "get": {
+        "tags": [
+          "v2"
+        ],
+        "summary": "list the SpaceAPI versions the validator knows",
+        "responses": {
+          "200": {
+            "description": "successful operation",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/SchemaInfo"
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/schemas/{version}": {
+      "get": {
+        "tags": [
+          "v2"
+        ],
+        "summary": "get the JSON schema documents of a version are validated against",
+        "parameters": [
+          {
+            "name": "version",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "example": "15"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "successful operation",
+            "content": {
+              "application/schema+json": {
+                "schema": {
+                  "type": "object"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "unknown version"
+          }
+        }
+      }
     }
   },
   "components": {
     "schemas": {
+      "SchemaInfo": {
+        "properties": {
+          "version": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "stable",
+              "draft",
+              "deprecated"
+            ]
+          },
+          "url": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "version",
+          "status",
+          "url"
+        ]
+      },
       "ServerInformation": {
         "properties": {
           "description": {
@@ -198,6 +585,17 @@ var openapi = `{
           "url": {
             "type": "string",
             "pattern": "uri"
+          },
+          "versions": {
+            "description": "SpaceAPI versions to validate against instead of the ones the endpoint declares",
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "deep": {
+            "description": "also fetch the logo, webcams, feeds and streams the endpoint links to and verify CORS with preflights",
+            "type": "boolean"
           }
         },
         "required": [
@@ -238,15 +636,43 @@ var openapi = `{
           "reachable": {
             "type": "boolean"
           },
+          "reachability": {
+            "$ref": "#/components/schemas/Reachability"
+          },
           "cors": {
+            "description": "the Access-Control-Allow-Origin header of the endpoint allows the validator origin",
             "type": "boolean"
           },
+          "corsChecks": {
+            "description": "CORS checks with preflights from the validator origin and a third party origin, only in deep mode",
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/CORSCheck"
+            }
+          },
           "contentType": {
             "type": "boolean"
           },
           "certValid": {
             "type": "boolean"
           },
+          "tls": {
+            "$ref": "#/components/schemas/TLSReport"
+          },
+          "redirects": {
+            "$ref": "#/components/schemas/RedirectReport"
+          },
+          "parseError": {
+            "$ref": "#/components/schemas/ParseError"
+          },
+          "bodyTooLarge": {
+            "description": "the endpoint body exceeds the maximum size and was not validated",
+            "type": "boolean"
+          },
+          "blocked": {
+            "description": "the endpoint (or a redirect target) resolves to a loopback, private, link-local or multicast address and was not fetched",
+            "type": "boolean"
+          },
           "checkedVersions": {
             "type": "array",
             "items": {
@@ -261,17 +687,37 @@ var openapi = `{
             "items": {
               "$ref": "#/components/schemas/SchemaError"
             }
-          }
-        },
-        "required": [
-          "valid",
-          "isHttps",
-          "httpsForward",
-          "reachable",
-          "cors",
-          "contentType",
-          "certValid"
-        ]
+          },
+          "warnings": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Warning"
+            }
+          },
+          "links": {
+            "description": "linked resources, only checked in deep mode",
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/LinkReport"
+            }
+          },
+          "patch": {
+            "description": "RFC 6902 JSON patch fixing the schema errors where the fix can be determined mechanically",
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/PatchOperation"
+            }
+          }
+        },
+        "required": [
+          "valid",
+          "isHttps",
+          "httpsForward",
+          "reachable",
+          "cors",
+          "contentType",
+          "certValid"
+        ]
       },
       "ValidateJsonV2Response": {
         "properties": {
@@ -295,6 +741,19 @@ var openapi = `{
             "items": {
               "$ref": "#/components/schemas/SchemaError"
             }
+          },
+          "warnings": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Warning"
+            }
+          },
+          "patch": {
+            "description": "RFC 6902 JSON patch fixing the schema errors where the fix can be determined mechanically",
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/PatchOperation"
+            }
           }
         },
         "required": [
@@ -302,6 +761,651 @@ var openapi = `{
           "message"
         ]
       },
+      "TLSReport": {
+        "description": "certificate and connection details of HTTPS endpoints",
+        "properties": {
+          "error": {
+            "description": "certificate verification error",
+            "type": "string"
+          },
+          "errorType": {
+            "type": "string",
+            "enum": [
+              "expired",
+              "hostnameMismatch",
+              "unknownAuthority",
+              "incompleteChain",
+              "invalid"
+            ]
+          },
+          "subject": {
+            "type": "string"
+          },
+          "sans": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "issuer": {
+            "type": "string"
+          },
+          "notBefore": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "notAfter": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "daysLeft": {
+            "type": "integer"
+          },
+          "warnings": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "version": {
+            "type": "string"
+          },
+          "cipherSuite": {
+            "type": "string"
+          }
+        }
+      },
+      "ParseError": {
+        "description": "why the endpoint body is not a JSON object, omitted if it is one",
+        "properties": {
+          "error": {
+            "type": "string"
+          },
+          "line": {
+            "type": "integer"
+          },
+          "column": {
+            "type": "integer"
+          },
+          "excerpt": {
+            "description": "sanitized part of the body around the error",
+            "type": "string"
+          },
+          "cause": {
+            "type": "string",
+            "enum": [
+              "html",
+              "bom",
+              "jsonp",
+              "truncated",
+              "notObject"
+            ]
+          },
+          "hint": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "error",
+          "excerpt"
+        ]
+      },
+      "Reachability": {
+        "description": "why the endpoint couldn't be fetched, omitted if it is reachable",
+        "properties": {
+          "class": {
+            "type": "string",
+            "enum": [
+              "dnsNotFound",
+              "dns",
+              "connectionRefused",
+              "timeout",
+              "tlsHandshake",
+              "httpStatus",
+              "blocked",
+              "redirect",
+              "other"
+            ]
+          },
+          "error": {
+            "type": "string"
+          },
+          "statusCode": {
+            "type": "integer"
+          },
+          "bodyExcerpt": {
+            "description": "start of the error response, for the httpStatus class",
+            "type": "string"
+          }
+        },
+        "required": [
+          "class",
+          "error"
+        ]
+      },
+      "CORSCheck": {
+        "description": "result of a GET and a preflight request sent from an origin",
+        "properties": {
+          "origin": {
+            "type": "string"
+          },
+          "simple": {
+            "description": "a plain GET from the origin may read the response",
+            "type": "boolean"
+          },
+          "preflight": {
+            "description": "the endpoint accepts a preflight for a GET with a Cache-Control header",
+            "type": "boolean"
+          },
+          "allowOrigin": {
+            "type": "string"
+          },
+          "allowMethods": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "allowHeaders": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "errors": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "RedirectReport": {
+        "description": "redirects followed when fetching the endpoint, omitted if there were none",
+        "properties": {
+          "hops": {
+            "type": "array",
+            "items": {
+              "properties": {
+                "url": {
+                  "type": "string"
+                },
+                "statusCode": {
+                  "type": "integer"
+                },
+                "location": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "downgrade": {
+            "description": "a redirect leads from https to http",
+            "type": "boolean"
+          },
+          "loop": {
+            "type": "boolean"
+          },
+          "tooMany": {
+            "type": "boolean"
+          },
+          "warnings": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "LinkReport": {
+        "description": "result of fetching a resource the endpoint links to",
+        "properties": {
+          "pointer": {
+            "type": "string"
+          },
+          "url": {
+            "type": "string"
+          },
+          "kind": {
+            "type": "string",
+            "enum": [
+              "image",
+              "page",
+              "feed",
+              "calendar",
+              "stream"
+            ]
+          },
+          "reachable": {
+            "type": "boolean"
+          },
+          "statusCode": {
+            "type": "integer"
+          },
+          "contentType": {
+            "type": "string"
+          },
+          "contentTypeValid": {
+            "type": "boolean"
+          },
+          "error": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "pointer",
+          "url",
+          "kind",
+          "reachable",
+          "contentTypeValid"
+        ]
+      },
+      "Warning": {
+        "description": "finding of a lint rule, doesn't make the document invalid",
+        "properties": {
+          "rule": {
+            "type": "string"
+          },
+          "severity": {
+            "type": "string",
+            "enum": [
+              "warning",
+              "info"
+            ]
+          },
+          "message": {
+            "type": "string"
+          },
+          "pointer": {
+            "type": "string"
+          },
+          "line": {
+            "type": "integer"
+          },
+          "column": {
+            "type": "integer"
+          }
+        },
+        "required": [
+          "rule",
+          "severity",
+          "message"
+        ]
+      },
+      "ValidateBatchV2": {
+        "properties": {
+          "items": {
+            "type": "array",
+            "items": {
+              "properties": {
+                "id": {
+                  "description": "defaults to the index of the item",
+                  "type": "string"
+                },
+                "url": {
+                  "type": "string",
+                  "pattern": "uri"
+                },
+                "document": {
+                  "type": "object"
+                }
+              }
+            }
+          },
+          "versions": {
+            "description": "SpaceAPI versions to validate against instead of the declared ones",
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "deep": {
+            "description": "also fetch the resources the endpoints link to",
+            "type": "boolean"
+          }
+        },
+        "required": [
+          "items"
+        ]
+      },
+      "ValidateBatchV2Response": {
+        "properties": {
+          "valid": {
+            "type": "boolean"
+          },
+          "results": {
+            "type": "object",
+            "additionalProperties": {
+              "$ref": "#/components/schemas/BatchResult"
+            }
+          }
+        },
+        "required": [
+          "valid",
+          "results"
+        ]
+      },
+      "BatchResult": {
+        "properties": {
+          "id": {
+            "type": "string"
+          },
+          "valid": {
+            "type": "boolean"
+          },
+          "url": {
+            "$ref": "#/components/schemas/ValidateUrlV2Response"
+          },
+          "json": {
+            "$ref": "#/components/schemas/ValidateJsonV2Response"
+          },
+          "error": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "id",
+          "valid"
+        ]
+      },
+      "ValidateDirectoryV2": {
+        "description": "either url or directory is required",
+        "properties": {
+          "url": {
+            "description": "URL of the directory",
+            "type": "string",
+            "pattern": "uri"
+          },
+          "directory": {
+            "description": "the directory, mapping space names to endpoint URLs",
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            }
+          },
+          "versions": {
+            "description": "SpaceAPI versions to validate against instead of the declared ones",
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "callback": {
+            "description": "URL the report, or {\"error\": …} if the job failed, is posted to once the job is finished",
+            "type": "string",
+            "pattern": "uri"
+          }
+        }
+      },
+      "DirectoryReport": {
+        "properties": {
+          "total": {
+            "type": "integer"
+          },
+          "results": {
+            "description": "number of spaces by result",
+            "type": "object",
+            "additionalProperties": {
+              "type": "integer"
+            }
+          },
+          "versions": {
+            "description": "number of spaces by checked version, the most frequent first",
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Count"
+            }
+          },
+          "failedChecks": {
+            "description": "number of spaces by failed check, the most frequent first",
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Count"
+            }
+          },
+          "spaces": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/SpaceReport"
+            }
+          }
+        },
+        "required": [
+          "total",
+          "results",
+          "versions",
+          "failedChecks",
+          "spaces"
+        ]
+      },
+      "Count": {
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "count": {
+            "type": "integer"
+          }
+        },
+        "required": [
+          "name",
+          "count"
+        ]
+      },
+      "SpaceReport": {
+        "properties": {
+          "space": {
+            "type": "string"
+          },
+          "url": {
+            "type": "string"
+          },
+          "result": {
+            "type": "string",
+            "enum": [
+              "valid",
+              "invalid",
+              "unreachable",
+              "error"
+            ]
+          },
+          "versions": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "failedChecks": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "schemaErrors": {
+            "type": "integer"
+          },
+          "message": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "space",
+          "url",
+          "result",
+          "schemaErrors"
+        ]
+      },
+      "JobV2Request": {
+        "allOf": [
+          {
+            "$ref": "#/components/schemas/ValidateUrlV2"
+          },
+          {
+            "properties": {
+              "callback": {
+                "description": "URL the result, or {\"error\": …} if the job failed, is posted to once the job is finished",
+                "type": "string",
+                "pattern": "uri"
+              }
+            }
+          }
+        ]
+      },
+      "Job": {
+        "properties": {
+          "id": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "queued",
+              "running",
+              "done",
+              "failed"
+            ]
+          },
+          "url": {
+            "description": "URL of the endpoint or of the directory, empty for directories sent inline",
+            "type": "string"
+          },
+          "callback": {
+            "type": "string"
+          },
+          "created": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "started": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "finished": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "result": {
+            "$ref": "#/components/schemas/ValidateUrlV2Response"
+          },
+          "directory": {
+            "$ref": "#/components/schemas/DirectoryReport"
+          },
+          "error": {
+            "type": "string"
+          },
+          "callbackError": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "id",
+          "status",
+          "created"
+        ]
+      },
+      "MigrateV2Response": {
+        "properties": {
+          "valid": {
+            "description": "whether the migrated document is valid for the target version",
+            "type": "boolean"
+          },
+          "from": {
+            "type": "string"
+          },
+          "to": {
+            "type": "string"
+          },
+          "document": {
+            "type": "object"
+          },
+          "changes": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/MigrationChange"
+            }
+          },
+          "todos": {
+            "description": "what couldn't be migrated automatically",
+            "type": "array",
+            "items": {
+              "properties": {
+                "pointer": {
+                  "type": "string"
+                },
+                "message": {
+                  "type": "string"
+                }
+              },
+              "required": [
+                "message"
+              ]
+            }
+          },
+          "schemaErrors": {
+            "description": "positions refer to the document indented by two spaces",
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/SchemaError"
+            }
+          }
+        },
+        "required": [
+          "valid",
+          "from",
+          "to",
+          "document",
+          "changes"
+        ]
+      },
+      "MigrationChange": {
+        "properties": {
+          "op": {
+            "type": "string",
+            "enum": [
+              "add",
+              "remove",
+              "replace",
+              "move"
+            ]
+          },
+          "pointer": {
+            "type": "string"
+          },
+          "from": {
+            "type": "string"
+          },
+          "message": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "op",
+          "pointer",
+          "message"
+        ]
+      },
+      "PatchOperation": {
+        "properties": {
+          "op": {
+            "type": "string",
+            "enum": [
+              "replace",
+              "move"
+            ]
+          },
+          "path": {
+            "type": "string"
+          },
+          "from": {
+            "type": "string"
+          },
+          "value": {}
+        },
+        "required": [
+          "op",
+          "path"
+        ]
+      },
       "SchemaError": {
         "properties": {
           "field": {
@@ -309,6 +1413,22 @@ var openapi = `{
           },
           "message": {
             "type": "string"
+          },
+          "pointer": {
+            "description": "JSON pointer of the offending value, empty for the document root",
+            "type": "string"
+          },
+          "line": {
+            "description": "line of the offending value in the validated document",
+            "type": "integer"
+          },
+          "column": {
+            "description": "column of the offending value in the validated document",
+            "type": "integer"
+          },
+          "suggestion": {
+            "description": "how the error can be fixed, if it is known",
+            "type": "string"
           }
         },
         "required": [
@@ -0,0 +1,252 @@
+// Package ratelimit limits the request rate per client
+package ratelimit
+
+import (
+	"golang.org/x/time/rate"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyHeader is the request header clients send their API key in
+const APIKeyHeader = "X-API-Key"
+
+// DefaultIdleTimeout is the time after which clients without requests are
+// forgotten, if not configured otherwise
+const DefaultIdleTimeout = time.Minute * 10
+
+// Options configure how clients are identified
+type Options struct {
+	// TrustedProxies are the networks whose X-Forwarded-For header is
+	// trusted to contain the address of the client
+	TrustedProxies []*net.IPNet
+	// APIKeys are the keys which get a limit of their own, independent of
+	// the address they are used from
+	APIKeys []string
+	// IdleTimeout defaults to DefaultIdleTimeout, it is raised to the time
+	// needed to refill an empty bucket so eviction never resets a limit early
+	IdleTimeout time.Duration
+}
+
+// Limiter keeps a token bucket per client
+type Limiter struct {
+	limit   rate.Limit
+	burst   int
+	idle    time.Duration
+	trusted []*net.IPNet
+	apiKeys map[string]bool
+
+	// OnReject is called for every rejected request, if set
+	OnReject func(r *http.Request)
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type client struct {
+	mu       sync.Mutex
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New returns a Limiter allowing limit requests per second with the given
+// burst to every client
+func New(limit float64, burst int, opts Options) *Limiter {
+	idle := opts.IdleTimeout
+	if idle == 0 {
+		idle = DefaultIdleTimeout
+	}
+	if limit > 0 {
+		refill := time.Duration(float64(burst) / limit * float64(time.Second))
+		if refill > idle {
+			idle = refill
+		}
+	}
+
+	apiKeys := map[string]bool{}
+	for _, key := range opts.APIKeys {
+		apiKeys[key] = true
+	}
+
+	return &Limiter{
+		limit:   rate.Limit(limit),
+		burst:   burst,
+		idle:    idle,
+		trusted: opts.TrustedProxies,
+		apiKeys: apiKeys,
+		clients: map[string]*client{},
+		now:     time.Now,
+	}
+}
+
+// Handler rejects requests of clients exceeding their limit with 429 and
+// adds the RateLimit-* headers to all responses
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.take(w, r, 1) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Take charges n more tokens to the client of a request admitted by Handler,
+// for requests costing more than one, e.g. validating several endpoints. If
+// the client doesn't have n tokens left, none are charged, the request is
+// rejected with 429 like by Handler and false is returned.
+func (l *Limiter) Take(w http.ResponseWriter, r *http.Request, n int) bool {
+	if n <= 0 {
+		return true
+	}
+	return l.take(w, r, n)
+}
+
+func (l *Limiter) take(w http.ResponseWriter, r *http.Request, n int) bool {
+	now := l.now()
+	c := l.client(l.Key(r), now)
+
+	c.mu.Lock()
+	reservation := c.limiter.ReserveN(now, n)
+	delay := reservation.DelayFrom(now)
+	if !reservation.OK() || delay > 0 {
+		reservation.CancelAt(now)
+	}
+	reset := l.resetAt(c.limiter, now)
+	c.mu.Unlock()
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(l.burst))
+	w.Header().Set("RateLimit-Reset", seconds(reset))
+
+	if !reservation.OK() || delay > 0 {
+		if l.OnReject != nil {
+			l.OnReject(r)
+		}
+		w.Header().Set("RateLimit-Remaining", "0")
+		// a request costing more than the burst can never be admitted
+		if reservation.OK() || n == 1 {
+			w.Header().Set("Retry-After", seconds(delay))
+		}
+		http.Error(w, http.StatusText(429), http.StatusTooManyRequests)
+		return false
+	}
+
+	remaining := l.burst - int(math.Ceil(reset.Seconds()*float64(l.limit)))
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	return true
+}
+
+// resetAt returns the time until the bucket is full again. x/time/rate
+// doesn't expose the number of tokens, so a reservation for the whole burst
+// is made and cancelled right away.
+func (l *Limiter) resetAt(limiter *rate.Limiter, now time.Time) time.Duration {
+	full := limiter.ReserveN(now, l.burst)
+	if !full.OK() {
+		return 0
+	}
+	reset := full.DelayFrom(now)
+	full.CancelAt(now)
+	return reset
+}
+
+// Key identifies the client sending the request, by its API key if it sent
+// a known one and by its address otherwise
+func (l *Limiter) Key(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" && l.apiKeys[key] {
+		return "key:" + key
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP returns the remote address of the request. If it is a trusted
+// proxy, the X-Forwarded-For header is followed from the right to the first
+// address which isn't a trusted proxy.
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !l.isTrusted(net.ParseIP(host)) {
+		return host
+	}
+
+	var forwarded []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		host = ip.String()
+		if !l.isTrusted(ip) {
+			break
+		}
+	}
+
+	return host
+}
+
+func (l *Limiter) isTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// client returns the bucket of a client, creating it if necessary, and
+// evicts idle clients from time to time
+func (l *Limiter) client(key string, now time.Time) *client {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > l.idle {
+		for k, c := range l.clients {
+			c.mu.Lock()
+			idle := now.Sub(c.lastSeen) > l.idle
+			c.mu.Unlock()
+			if idle {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+
+	c.mu.Lock()
+	c.lastSeen = now
+	c.mu.Unlock()
+
+	return c
+}
+
+// Len returns the number of clients currently tracked
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.clients)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
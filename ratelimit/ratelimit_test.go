@@ -0,0 +1,215 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestLimiter(limit float64, burst int, opts Options) (*Limiter, *clock) {
+	c := &clock{t: time.Unix(1600000000, 0)}
+	l := New(limit, burst, opts)
+	l.now = c.now
+	return l, c
+}
+
+func forgeRequest(t *testing.T, handler http.Handler, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/v2/validateURL", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestLimitPerClient(t *testing.T) {
+	l, _ := newTestLimiter(1, 2, Options{})
+	handler := l.Handler(ok)
+
+	for i := 0; i < 2; i++ {
+		if rr := forgeRequest(t, handler, "192.0.2.1:1234", nil); rr.Code != http.StatusOK {
+			t.Fatalf("request %d was rejected", i)
+		}
+	}
+
+	rr := forgeRequest(t, handler, "192.0.2.1:1235", nil)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			rr.Code, http.StatusTooManyRequests)
+	}
+
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("wrong Retry-After header: got %v want %v", retryAfter, "1")
+	}
+
+	if rr := forgeRequest(t, handler, "192.0.2.2:1234", nil); rr.Code != http.StatusOK {
+		t.Errorf("other client was rejected")
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	l, c := newTestLimiter(2, 10, Options{})
+	handler := l.Handler(ok)
+
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 4; i++ {
+		rr = forgeRequest(t, handler, "192.0.2.1:1234", nil)
+	}
+
+	expected := map[string]string{
+		"RateLimit-Limit":     "10",
+		"RateLimit-Remaining": "6",
+		"RateLimit-Reset":     "2",
+	}
+	for header, want := range expected {
+		if got := rr.Header().Get(header); got != want {
+			t.Errorf("wrong %s header: got %v want %v", header, got, want)
+		}
+	}
+
+	c.t = c.t.Add(time.Second)
+	rr = forgeRequest(t, handler, "192.0.2.1:1234", nil)
+	if got := rr.Header().Get("RateLimit-Remaining"); got != "7" {
+		t.Errorf("wrong RateLimit-Remaining header after refill: got %v want %v", got, "7")
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	l, _ := newTestLimiter(1, 1, Options{TrustedProxies: []*net.IPNet{proxies}})
+
+	cases := []struct {
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"192.0.2.1:1234", "", "ip:192.0.2.1"},
+		{"192.0.2.1:1234", "198.51.100.7", "ip:192.0.2.1"},
+		{"10.0.0.1:1234", "198.51.100.7", "ip:198.51.100.7"},
+		{"10.0.0.1:1234", "203.0.113.9, 198.51.100.7, 10.0.0.2", "ip:198.51.100.7"},
+		{"10.0.0.1:1234", "", "ip:10.0.0.1"},
+		{"10.0.0.1:1234", "garbage", "ip:10.0.0.1"},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = c.remoteAddr
+		if c.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+
+		if key := l.Key(req); key != c.want {
+			t.Errorf("wrong key for %s / %q: got %v want %v", c.remoteAddr, c.forwarded, key, c.want)
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	l, _ := newTestLimiter(1, 1, Options{APIKeys: []string{"secret"}})
+	handler := l.Handler(ok)
+
+	if rr := forgeRequest(t, handler, "192.0.2.1:1234", nil); rr.Code != http.StatusOK {
+		t.Fatalf("first request was rejected")
+	}
+
+	withKey := map[string]string{APIKeyHeader: "secret"}
+	if rr := forgeRequest(t, handler, "192.0.2.1:1234", withKey); rr.Code != http.StatusOK {
+		t.Errorf("request with api key shares the limit of the address")
+	}
+
+	unknownKey := map[string]string{APIKeyHeader: "guessed"}
+	if rr := forgeRequest(t, handler, "192.0.2.1:1234", unknownKey); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("unknown api key got a limit of its own")
+	}
+}
+
+func TestEvictIdleClients(t *testing.T) {
+	l, c := newTestLimiter(1, 1, Options{IdleTimeout: time.Minute})
+	handler := l.Handler(ok)
+
+	forgeRequest(t, handler, "192.0.2.1:1234", nil)
+	forgeRequest(t, handler, "192.0.2.2:1234", nil)
+	if n := l.Len(); n != 2 {
+		t.Fatalf("wrong number of clients: got %v want %v", n, 2)
+	}
+
+	c.t = c.t.Add(time.Second * 30)
+	forgeRequest(t, handler, "192.0.2.2:1234", nil)
+
+	c.t = c.t.Add(time.Second * 45)
+	forgeRequest(t, handler, "192.0.2.3:1234", nil)
+
+	if n := l.Len(); n != 2 {
+		t.Errorf("idle client was not evicted: got %v clients want %v", n, 2)
+	}
+}
+
+func TestOnReject(t *testing.T) {
+	l, _ := newTestLimiter(1, 1, Options{})
+	rejected := 0
+	l.OnReject = func(*http.Request) {
+		rejected++
+	}
+	handler := l.Handler(ok)
+
+	for i := 0; i < 3; i++ {
+		forgeRequest(t, handler, "192.0.2.1:1234", nil)
+	}
+
+	if rejected != 2 {
+		t.Errorf("wrong number of rejections: got %v want %v", rejected, 2)
+	}
+}
+
+func TestTake(t *testing.T) {
+	l, _ := newTestLimiter(1, 6, Options{})
+	taken := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.Take(w, r, 3) {
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+	handler := l.Handler(taken)
+
+	if rr := forgeRequest(t, handler, "192.0.2.1:1234", nil); rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			rr.Code, http.StatusOK)
+	}
+
+	rr := forgeRequest(t, handler, "192.0.2.1:1234", nil)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			rr.Code, http.StatusTooManyRequests)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Errorf("wrong Retry-After header: got %v want %v", retryAfter, "2")
+	}
+
+	// the rejected Take charged nothing, only the token of the request
+	// itself, so a plain request still passes
+	if rr := forgeRequest(t, l.Handler(ok), "192.0.2.1:1234", nil); rr.Code != http.StatusOK {
+		t.Errorf("tokens of the rejected request were charged")
+	}
+}
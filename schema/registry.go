@@ -0,0 +1,164 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// schemaFile matches the file names of the spaceapi/schema repository, e.g.
+// 14.json or 16-draft.json
+var schemaFile = regexp.MustCompile(`^(\d+)(-draft)?\.json$`)
+
+// LoadDir loads the schemas in dir on top of the builtin ones, so schemas of
+// the same version are replaced and new versions added. It fails if any of
+// the schema files is invalid.
+func LoadDir(dir string) (*Set, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &Set{schemas: map[string]*Schema{}}
+	for version, schema := range Builtin().schemas {
+		set.schemas[version] = schema
+	}
+
+	loaded := 0
+	for _, file := range files {
+		match := schemaFile.FindStringSubmatch(file.Name())
+		if match == nil || file.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		schema, err := newSchema(match[1], raw, match[2] != "", path)
+		if err != nil {
+			return nil, err
+		}
+		set.schemas[schema.Version] = schema
+		loaded++
+	}
+
+	if loaded == 0 {
+		return nil, fmt.Errorf("no schema files found in %s", dir)
+	}
+
+	return set, nil
+}
+
+// Registry holds the current set of schemas. If it is configured with a
+// directory, the set can be reloaded from it while requests are served.
+type Registry struct {
+	dir     string
+	current atomic.Value
+
+	mu    sync.Mutex
+	stamp string
+}
+
+// NewRegistry returns a registry with the schemas of dir, or the builtin
+// schemas if dir is empty
+func NewRegistry(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+	if dir == "" {
+		r.current.Store(Builtin())
+		return r, nil
+	}
+
+	r.stamp = dirStamp(dir)
+	set, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	r.current.Store(set)
+	return r, nil
+}
+
+// Current returns the current set of schemas. A nil registry returns the
+// builtin schemas.
+func (r *Registry) Current() *Set {
+	if r == nil {
+		return Builtin()
+	}
+	return r.current.Load().(*Set)
+}
+
+// Reload loads the schemas again if the files in the directory changed. The
+// new set replaces the current one only if it could be loaded completely.
+func (r *Registry) Reload() (bool, error) {
+	if r.dir == "" {
+		return false, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp := dirStamp(r.dir)
+	if stamp == r.stamp {
+		return false, nil
+	}
+	// remember the stamp even if loading fails, so a broken file is only
+	// reported once and picked up again as soon as it is fixed
+	r.stamp = stamp
+
+	set, err := LoadDir(r.dir)
+	if err != nil {
+		return false, err
+	}
+	r.current.Store(set)
+	return true, nil
+}
+
+// Watch checks the directory for changes every interval until ctx is done
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	if r.dir == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				log.Printf("keeping current schemas, reloading %s failed: %v", r.dir, err)
+			} else if reloaded {
+				log.Printf("reloaded schemas from %s, versions %s", r.dir, strings.Join(r.Current().Versions(), ", "))
+			}
+		}
+	}
+}
+
+// dirStamp summarizes the names, sizes and modification times of the schema
+// files in dir, so changes can be detected without reading them
+func dirStamp(dir string) string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		if schemaFile.MatchString(file.Name()) {
+			fmt.Fprintf(&b, "%s:%d:%d\n", file.Name(), file.Size(), file.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var draftSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"required": ["space", "rocket"]
+}`
+
+func schemaDir(t *testing.T, files map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "schemas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		writeSchema(t, dir, name, content)
+	}
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func writeSchema(t *testing.T, dir, name, content string) {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir, cleanup := schemaDir(t, map[string]string{
+		"17-draft.json": draftSchema,
+		"package.json":  `{"name": "schema"}`,
+		"README.md":     "# schemas",
+	})
+	defer cleanup()
+
+	set, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, ok := set.Schema("17")
+	if !ok {
+		t.Fatalf("schema 17 not loaded, versions %v", set.Versions())
+	}
+	if !schema.Draft || schema.Source != filepath.Join(dir, "17-draft.json") {
+		t.Errorf("wrong schema: draft %v source %v", schema.Draft, schema.Source)
+	}
+
+	if schema, ok := set.Schema("14"); !ok || schema.Source != SourceBuiltin {
+		t.Errorf("builtin schema 14 missing")
+	}
+	if _, ok := set.Schema("package"); ok {
+		t.Errorf("package.json loaded as a schema")
+	}
+
+	res, err := set.Validate(space13, []string{"17"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Valid || len(res.Errors) != 1 {
+		t.Errorf("wrong result against schema 17: %+v", res)
+	}
+}
+
+func TestLoadDirInvalid(t *testing.T) {
+	dir, cleanup := schemaDir(t, map[string]string{"17.json": `{"type": 42}`})
+	defer cleanup()
+
+	if _, err := LoadDir(dir); err == nil {
+		t.Errorf("invalid schema loaded")
+	}
+
+	empty, cleanupEmpty := schemaDir(t, nil)
+	defer cleanupEmpty()
+
+	if _, err := LoadDir(empty); err == nil {
+		t.Errorf("empty directory loaded")
+	}
+}
+
+func TestRegistryReload(t *testing.T) {
+	dir, cleanup := schemaDir(t, map[string]string{"17-draft.json": draftSchema})
+	defer cleanup()
+
+	registry, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := registry.Current()
+
+	reloaded, err := registry.Reload()
+	if reloaded || err != nil {
+		t.Errorf("reloaded unchanged directory: %v %v", reloaded, err)
+	}
+
+	// a broken file keeps the current set
+	writeSchema(t, dir, "18-draft.json", `{"type": 42}`)
+	reloaded, err = registry.Reload()
+	if reloaded || err == nil {
+		t.Errorf("reloaded broken directory: %v %v", reloaded, err)
+	}
+	if registry.Current() != before {
+		t.Errorf("current set replaced by broken directory")
+	}
+
+	writeSchema(t, dir, "18-draft.json", draftSchema)
+	// make sure the modification is noticed on file systems with coarse
+	// timestamps
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(filepath.Join(dir, "18-draft.json"), future, future)
+
+	reloaded, err = registry.Reload()
+	if !reloaded || err != nil {
+		t.Fatalf("directory not reloaded: %v %v", reloaded, err)
+	}
+	if _, ok := registry.Current().Schema("18"); !ok {
+		t.Errorf("schema 18 missing after reload")
+	}
+	if _, ok := before.Schema("18"); ok {
+		t.Errorf("previous set modified")
+	}
+}
+
+func TestRegistryBuiltin(t *testing.T) {
+	registry, err := NewRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registry.Current() != Builtin() {
+		t.Errorf("registry without directory doesn't use the builtin schemas")
+	}
+
+	var nilRegistry *Registry
+	if nilRegistry.Current() != Builtin() {
+		t.Errorf("nil registry doesn't use the builtin schemas")
+	}
+}
+
+func TestBuiltinDraft(t *testing.T) {
+	if schema, ok := Builtin().Schema("16"); !ok || !schema.Draft {
+		t.Errorf("schema 16 not marked as draft")
+	}
+	if schema, ok := Builtin().Schema("15"); !ok || schema.Draft {
+		t.Errorf("schema 15 marked as draft")
+	}
+}
@@ -0,0 +1,290 @@
+// Package schema validates documents against the SpaceAPI schemas
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	spaceapivalidator "github.com/spaceapi-community/go-spaceapi-validator"
+	"github.com/xeipuuv/gojsonschema"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceBuiltin is the source of the schemas compiled into
+// go-spaceapi-validator
+const SourceBuiltin = "builtin"
+
+// defaultVersion is validated against if a document declares no version
+const defaultVersion = "14"
+
+// Status of a schema version
+const (
+	StatusStable     = "stable"
+	StatusDraft      = "draft"
+	StatusDeprecated = "deprecated"
+)
+
+// deprecatedVersions are superseded by v14, which replaced the api field by
+// api_compatibility
+var deprecatedVersions = map[string]bool{
+	"12": true,
+	"13": true,
+}
+
+// UnknownVersionError is returned when validating against a version there is
+// no schema for
+type UnknownVersionError struct {
+	Version string
+	Known   []string
+}
+
+func (e *UnknownVersionError) Error() string {
+	return fmt.Sprintf("unknown SpaceAPI version %q, known versions are %s", e.Version, strings.Join(e.Known, ", "))
+}
+
+// Schema is the JSON schema of a SpaceAPI version
+type Schema struct {
+	Version string
+	Draft   bool
+	// Source is the file the schema was loaded from, or SourceBuiltin
+	Source string
+	Raw    []byte
+
+	compiled *gojsonschema.Schema
+}
+
+// Status returns whether the version is stable, a draft or deprecated
+func (s *Schema) Status() string {
+	switch {
+	case s.Draft:
+		return StatusDraft
+	case deprecatedVersions[s.Version]:
+		return StatusDeprecated
+	default:
+		return StatusStable
+	}
+}
+
+func newSchema(version string, raw []byte, draft bool, source string) (*Schema, error) {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %v", source, err)
+	}
+	return &Schema{Version: version, Draft: draft, Source: source, Raw: raw, compiled: compiled}, nil
+}
+
+// Set is an immutable set of schemas by version
+type Set struct {
+	schemas map[string]*Schema
+}
+
+var builtin = mustBuiltin()
+
+// Builtin returns the schemas compiled into go-spaceapi-validator
+func Builtin() *Set {
+	return builtin
+}
+
+func mustBuiltin() *Set {
+	set := &Set{schemas: map[string]*Schema{}}
+	for version, raw := range spaceapivalidator.SpaceAPISchemas {
+		var meta struct {
+			ID string `json:"$id"`
+		}
+		_ = json.Unmarshal([]byte(raw), &meta)
+
+		schema, err := newSchema(version, []byte(raw), strings.HasSuffix(meta.ID, "-draft.json"), SourceBuiltin)
+		if err != nil {
+			panic(err)
+		}
+		set.schemas[version] = schema
+	}
+	return set
+}
+
+// Versions returns the versions there are schemas for, in ascending order
+func (s *Set) Versions() []string {
+	var versions []string
+	for version := range s.schemas {
+		versions = append(versions, version)
+	}
+	sortVersions(versions)
+	return versions
+}
+
+// Latest returns the newest stable version, documents are migrated to it by
+// default
+func (s *Set) Latest() string {
+	var latest string
+	for _, version := range s.Versions() {
+		if s.schemas[version].Status() == StatusStable {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// Schema returns the schema of a version
+func (s *Set) Schema(version string) (*Schema, bool) {
+	schema, ok := s.schemas[normalize(version)]
+	return schema, ok
+}
+
+// ParseVersions normalizes a list of versions like "15" or "0.13" and checks
+// that there are schemas for them. Duplicates are removed.
+func (s *Set) ParseVersions(list []string) ([]string, error) {
+	var versions []string
+	seen := map[string]bool{}
+	for _, version := range list {
+		version = normalize(version)
+		if version == "" || seen[version] {
+			continue
+		}
+		if _, ok := s.schemas[version]; !ok {
+			return nil, &UnknownVersionError{Version: version, Known: s.Versions()}
+		}
+		seen[version] = true
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ErrorTypeUnsupportedVersion is the type of the error added for declared
+// versions there is no schema for
+const ErrorTypeUnsupportedVersion = "unsupported_version"
+
+// Result is the result of a validation. Details has an entry for each entry
+// of Errors.
+type Result struct {
+	spaceapivalidator.ValidationResult
+	Details []ErrorDetail
+}
+
+// ErrorDetail tells what kind of error a schema error is
+type ErrorDetail struct {
+	Version string
+	// Type is the error type of gojsonschema, like required or invalid_type
+	Type string
+	// Details are the parameters of the error, like the missing property of
+	// required errors
+	Details map[string]interface{}
+}
+
+// Validate validates document against the given versions. If no versions are
+// given, the versions the document declares in api and api_compatibility are
+// used, like spaceapivalidator.Validate does.
+func (s *Set) Validate(document []byte, versions []string) (Result, error) {
+	result := Result{ValidationResult: spaceapivalidator.ValidationResult{Valid: true}}
+	if len(document) == 0 {
+		return result, fmt.Errorf("document is empty")
+	}
+
+	forced := len(versions) > 0
+	if !forced {
+		var err error
+		versions, err = declaredVersions(document)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	documentLoader := gojsonschema.NewBytesLoader(document)
+	for _, version := range versions {
+		schema, ok := s.schemas[version]
+		if !ok && forced {
+			return result, &UnknownVersionError{Version: version, Known: s.Versions()}
+		}
+		if !ok {
+			unsupported := []spaceapivalidator.ResultError{{
+				Field:       "api_compatibility",
+				Context:     "(root).api_compatibility",
+				Description: fmt.Sprintf("Endpoint declares compatibility with schema version %s, which isn't supported", version),
+			}}
+			result.Valid = false
+			result.Schemas = append(result.Schemas, spaceapivalidator.VersionValidationResult{Version: version, Errors: unsupported})
+			result.Errors = append(result.Errors, unsupported...)
+			result.Details = append(result.Details, ErrorDetail{Version: version, Type: ErrorTypeUnsupportedVersion})
+			continue
+		}
+
+		res, err := schema.compiled.Validate(documentLoader)
+		if err != nil {
+			result.Valid = false
+			return result, err
+		}
+
+		versionResult := spaceapivalidator.VersionValidationResult{Version: version, Valid: res.Valid()}
+		for _, resultError := range res.Errors() {
+			versionResult.Errors = append(versionResult.Errors, spaceapivalidator.ResultError{
+				Field:       resultError.Field(),
+				Context:     resultError.Context().String(),
+				Description: resultError.Description(),
+			})
+			result.Details = append(result.Details, ErrorDetail{
+				Version: version,
+				Type:    resultError.Type(),
+				Details: resultError.Details(),
+			})
+		}
+
+		result.Schemas = append(result.Schemas, versionResult)
+		result.Errors = append(result.Errors, versionResult.Errors...)
+		if !res.Valid() {
+			result.Valid = false
+		}
+	}
+
+	return result, nil
+}
+
+// declaredVersions returns the versions a document declares in
+// api_compatibility and, for versions before v14, api
+func declaredVersions(document []byte) ([]string, error) {
+	var declared struct {
+		API              interface{} `json:"api"`
+		APICompatibility []string    `json:"api_compatibility"`
+	}
+	err := json.Unmarshal(document, &declared)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := declared.APICompatibility
+	if declared.API != nil {
+		versions = append(versions, strings.Replace(fmt.Sprintf("%v", declared.API), "0.", "", 1))
+	}
+	if len(versions) == 0 {
+		versions = []string{defaultVersion}
+	}
+	return versions, nil
+}
+
+// SplitVersions splits a comma separated list of versions, as used in query
+// parameters and flags
+func SplitVersions(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+func normalize(version string) string {
+	version = strings.TrimSpace(version)
+	return strings.TrimPrefix(version, "0.")
+}
+
+// sortVersions sorts numeric versions numerically and others after them
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		a, errA := strconv.Atoi(versions[i])
+		b, errB := strconv.Atoi(versions[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		if errA == nil || errB == nil {
+			return errA == nil
+		}
+		return versions[i] < versions[j]
+	})
+}
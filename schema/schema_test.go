@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+var space13 = []byte(`{
+	"api": "0.13",
+	"space": "my cool space",
+	"logo": "https://example.com/logo.png",
+	"url": "https://example.com",
+	"location": {
+		"address": "Ulmer Strasse 255, 70327 Stuttgart, Germany",
+		"lon": 9.236,
+		"lat": 48.777
+	},
+	"state": {
+		"open": false
+	},
+	"contact": {
+	},
+	"issue_report_channels": [
+		"email"
+	]
+}`)
+
+func TestVersions(t *testing.T) {
+	want := []string{"12", "13", "14", "15", "16"}
+	if got := Builtin().Versions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong versions: got %v want %v", got, want)
+	}
+}
+
+func TestParseVersions(t *testing.T) {
+	got, err := Builtin().ParseVersions([]string{"15", " 0.13", "15", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"15", "13"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong versions: got %v want %v", got, want)
+	}
+
+	_, err = Builtin().ParseVersions([]string{"15", "99"})
+	if unknown, ok := err.(*UnknownVersionError); !ok || unknown.Version != "99" {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestSplitVersions(t *testing.T) {
+	if got := SplitVersions(" "); got != nil {
+		t.Errorf("wrong versions: got %v want nil", got)
+	}
+	if got, want := SplitVersions("14,15"), []string{"14", "15"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong versions: got %v want %v", got, want)
+	}
+}
+
+func TestValidateDeclaredVersions(t *testing.T) {
+	res, err := Builtin().Validate(space13, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Valid || len(res.Schemas) != 1 || res.Schemas[0].Version != "13" {
+		t.Errorf("wrong result: %+v", res)
+	}
+}
+
+func TestValidateForcedVersions(t *testing.T) {
+	res, err := Builtin().Validate(space13, []string{"13", "15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Schemas) != 2 || res.Schemas[0].Version != "13" || res.Schemas[1].Version != "15" {
+		t.Fatalf("wrong schemas: %+v", res.Schemas)
+	}
+	if !res.Schemas[0].Valid {
+		t.Errorf("document invalid against 13: %+v", res.Schemas[0].Errors)
+	}
+	if res.Schemas[1].Valid || res.Valid {
+		t.Errorf("document valid against 15")
+	}
+	if len(res.Errors) == 0 {
+		t.Errorf("errors missing")
+	}
+}
+
+func TestValidateUnknownVersion(t *testing.T) {
+	_, err := Builtin().Validate(space13, []string{"99"})
+	if _, ok := err.(*UnknownVersionError); !ok {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestSchemaStatus(t *testing.T) {
+	want := map[string]string{
+		"12": StatusDeprecated,
+		"14": StatusStable,
+		"16": StatusDraft,
+	}
+	for version, status := range want {
+		schema, ok := Builtin().Schema(version)
+		if !ok {
+			t.Fatalf("schema %v missing", version)
+		}
+		if schema.Status() != status {
+			t.Errorf("wrong status of %v: got %v want %v", version, schema.Status(), status)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	if got := Builtin().Latest(); got != "15" {
+		t.Errorf("wrong latest version: got %v want %v", got, "15")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"github.com/spaceapi/validator/config"
+	"github.com/spaceapi/validator/metrics"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// inFlight counts the requests which are currently being handled
+type inFlight struct {
+	n int64
+}
+
+func (f *inFlight) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&f.n, 1)
+		defer atomic.AddInt64(&f.n, -1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *inFlight) count() int64 {
+	return atomic.LoadInt64(&f.n)
+}
+
+// newServer returns a http.Server with the timeouts from the configuration.
+// Requests are tracked by requests and their contexts are derived from base,
+// so cancelling base aborts outstanding endpoint fetches.
+func newServer(cfg config.Config, handler http.Handler, requests *inFlight, base context.Context) *http.Server {
+	return &http.Server{
+		Addr:         cfg.ListenAddr,
+		Handler:      requests.track(handler),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return base
+		},
+	}
+}
+
+// newMetricsServer returns a http.Server serving the Prometheus metrics at
+// /metrics. It listens on an address of its own, so the metrics aren't
+// exposed next to the public API.
+func newMetricsServer(cfg config.Config) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	return &http.Server{
+		Addr:         cfg.MetricsListenAddr,
+		Handler:      mux,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
+
+// serve runs srv on ln until a signal arrives on stop. It then stops
+// accepting new connections and waits up to timeout for outstanding
+// requests and, via stopJobs, for running validation jobs before cancelling
+// them via abort.
+func serve(srv *http.Server, ln net.Listener, stop <-chan os.Signal, timeout time.Duration, requests *inFlight, stopJobs func(), abort context.CancelFunc) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-stop:
+		log.Printf("received %v, shutting down...", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		err := srv.Shutdown(ctx)
+		if err == nil {
+			err = wait(ctx, stopJobs)
+		}
+		done <- err
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Printf("shutdown deadline exceeded, aborting %d outstanding requests and the running jobs", requests.count())
+				abort()
+				_ = srv.Close()
+				return err
+			}
+			log.Println("all requests and jobs drained, validator stopped")
+			return nil
+		case <-ticker.C:
+			log.Printf("waiting for %d outstanding requests...", requests.count())
+		}
+	}
+}
+
+// wait calls stop and returns once it returned or ctx is done
+func wait(ctx context.Context, stop func()) error {
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,6 +3,10 @@ package v1
 import (
 	"encoding/json"
 	spaceapivalidator "github.com/spaceapi-community/go-spaceapi-validator"
+	"github.com/spaceapi/validator/config"
+	"github.com/spaceapi/validator/internal/httpbody"
+	"github.com/spaceapi/validator/metrics"
+	"github.com/spaceapi/validator/ratelimit"
 	"goji.io"
 	"goji.io/pat"
 	"net/http"
@@ -24,10 +28,20 @@ type validationResponse struct {
 }
 
 // GetSubMux returns the versions subrouter
-func GetSubMux() *goji.Mux {
+func GetSubMux(cfg config.Config) *goji.Mux {
 	v1 := goji.SubMux()
 	v1.HandleFunc(pat.Get("/"), info)
-	v1.HandleFunc(pat.Post("/validate/"), validate)
+	limiter := ratelimit.New(cfg.JSONRateLimit, cfg.JSONRateBurst, cfg.LimitOptions())
+	limiter.OnReject = func(*http.Request) {
+		metrics.RateLimited(metrics.RouteV1Validate)
+	}
+	v1.Handle(
+		pat.Post("/validate/"),
+		metrics.Instrument(
+			metrics.RouteV1Validate,
+			limiter.Handler(httpbody.MaxBytes(http.HandlerFunc(validate), cfg.MaxRequestBodySize)),
+		),
+	)
 
 	v1.HandleFunc(pat.Get("/validate/"), func(writer http.ResponseWriter, _ *http.Request) {
 		writer.WriteHeader(405)
@@ -63,9 +77,9 @@ func validate(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	var req validationRequest
-	err := json.NewDecoder(request.Body).Decode(&req)
+	err := httpbody.DecodeStrict(request.Body, &req)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusBadRequest)
+		httpbody.Error(writer, err, http.StatusBadRequest)
 		return
 	}
 
@@ -81,6 +95,8 @@ func validate(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	metrics.Validation(metrics.RouteV1Validate, res.Valid)
+
 	var errMsg string
 	for _, validatorError := range res.Errors {
 		errMsg = errMsg + validatorError.Context + ": " + validatorError.Description + "\n"
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"encoding/json"
+	"github.com/spaceapi/validator/internal/httpbody"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -160,3 +161,34 @@ func TestServerInfo(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestValidateBodyTooLarge(t *testing.T) {
+	req, err := http.NewRequest("POST", "/v1/validate", strings.NewReader(validSpace))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	httpbody.MaxBytes(http.HandlerFunc(validate), 64).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestValidateTrailingData(t *testing.T) {
+	req, err := http.NewRequest("POST", "/v1/validate", strings.NewReader(validSpace+" {}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(validate)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
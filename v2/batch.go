@@ -0,0 +1,283 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/spaceapi/validator/config"
+	"github.com/spaceapi/validator/internal/httpbody"
+	"github.com/spaceapi/validator/metrics"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentTypeNDJSON is the content type of streamed batch results, one JSON
+// object per line
+const ContentTypeNDJSON = "application/x-ndjson"
+
+type batchRequest struct {
+	Items    []batchItem `json:"items"`
+	Versions []string    `json:"versions"`
+	Deep     bool        `json:"deep"`
+}
+
+// batchItem is either the URL of an endpoint or an inline document
+type batchItem struct {
+	ID       string          `json:"id"`
+	URL      string          `json:"url"`
+	Document json.RawMessage `json:"document"`
+}
+
+// BatchResult is the result of validating one item of a batch. Either URL or
+// JSON is set, depending on the kind of item, or Error if it couldn't be
+// validated.
+type BatchResult struct {
+	ID    string                  `json:"id"`
+	Valid bool                    `json:"valid"`
+	URL   *URLValidationResponse  `json:"url,omitempty"`
+	JSON  *JSONValidationResponse `json:"json,omitempty"`
+	Error string                  `json:"error,omitempty"`
+}
+
+// BatchResponse holds the results of a batch by item ID
+type BatchResponse struct {
+	Valid   bool                   `json:"valid"`
+	Results map[string]BatchResult `json:"results"`
+}
+
+func (s *server) validateBatch(writer http.ResponseWriter, request *http.Request) {
+	if request.Body == nil {
+		http.Error(writer, "body can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	var batchReq batchRequest
+	err := httpbody.DecodeStrict(request.Body, &batchReq)
+	if err != nil {
+		httpbody.Error(writer, err, http.StatusBadRequest)
+		return
+	}
+
+	err = checkBatch(batchReq.Items, s.cfg.BatchMaxItems, maxBatchURLs(s.cfg))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the request itself was charged one token, every further endpoint to
+	// fetch costs another one
+	if s.batchLimit != nil && !s.batchLimit.Take(writer, request, urlItems(batchReq.Items)-1) {
+		return
+	}
+
+	schemas := s.schemas.Current()
+	versions, err := schemas.ParseVersions(batchReq.Versions)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := ValidationOptions{Versions: versions, Schemas: schemas, Deep: batchReq.Deep}
+
+	ctx := request.Context()
+	if budget := writeBudget(s.cfg); budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	results := s.runBatch(ctx, metrics.RouteV2Batch, batchReq.Items, opts)
+
+	if streamBatch(request) {
+		writer.Header().Add("Content-Type", ContentTypeNDJSON)
+		writer.WriteHeader(http.StatusOK)
+		flusher, _ := writer.(http.Flusher)
+		encoder := json.NewEncoder(writer)
+		for result := range results {
+			if err := encoder.Encode(result); err != nil {
+				// the client is gone, the remaining items are aborted as the
+				// request context is canceled
+				go func() {
+					for range results {
+					}
+				}()
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return
+	}
+
+	resp := BatchResponse{Valid: true, Results: map[string]BatchResult{}}
+	for result := range results {
+		resp.Results[result.ID] = result
+		resp.Valid = resp.Valid && result.Valid
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(resp)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// maxBatchURLs returns how many endpoints a batch may have so that they can
+// be fetched within the writeBudget, if none of them takes longer than the
+// FetchTimeout. It is 0 if there is no limit.
+func maxBatchURLs(cfg config.Config) int {
+	budget := writeBudget(cfg)
+	if budget == 0 || cfg.FetchTimeout <= 0 {
+		return 0
+	}
+
+	parallelism := cfg.BatchParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	rounds := int(budget / cfg.FetchTimeout)
+	if rounds < 1 {
+		rounds = 1
+	}
+	return parallelism * rounds
+}
+
+// urlItems returns the number of items which are endpoints to fetch
+func urlItems(items []batchItem) int {
+	n := 0
+	for _, item := range items {
+		if item.URL != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// checkBatch assigns the index as ID to items without one and checks that
+// the IDs are unique and every item is either a URL or a document. At most
+// maxURLs items may be URLs, unless it is 0.
+func checkBatch(items []batchItem, maxItems, maxURLs int) error {
+	if len(items) == 0 {
+		return fmt.Errorf("batch has no items")
+	}
+	if len(items) > maxItems {
+		return fmt.Errorf("batch has %d items, at most %d are allowed", len(items), maxItems)
+	}
+	if urls := urlItems(items); maxURLs > 0 && urls > maxURLs {
+		return fmt.Errorf("batch has %d urls, at most %d can be validated before the write timeout", urls, maxURLs)
+	}
+
+	seen := map[string]bool{}
+	for i := range items {
+		item := &items[i]
+		if item.ID == "" {
+			item.ID = strconv.Itoa(i)
+		}
+		if seen[item.ID] {
+			return fmt.Errorf("duplicate item id %q", item.ID)
+		}
+		seen[item.ID] = true
+
+		if (item.URL == "") == (len(item.Document) == 0) {
+			return fmt.Errorf("item %q must have either a url or a document", item.ID)
+		}
+	}
+	return nil
+}
+
+// streamBatch returns whether the results are streamed as NDJSON
+func streamBatch(request *http.Request) bool {
+	if format := request.URL.Query().Get("format"); format != "" {
+		return format == "ndjson"
+	}
+	return strings.Contains(request.Header.Get("Accept"), ContentTypeNDJSON)
+}
+
+// runBatch validates the items with at most BatchParallelism at once and
+// sends the results in the order they are done. The validations are recorded
+// in the metrics of route.
+func (s *server) runBatch(ctx context.Context, route string, items []batchItem, opts ValidationOptions) <-chan BatchResult {
+	workers := s.cfg.BatchParallelism
+	if workers > len(items) {
+		workers = len(items)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan batchItem)
+	results := make(chan BatchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				if ctx.Err() != nil {
+					results <- BatchResult{ID: item.ID, Error: errBatchAborted(ctx)}
+					continue
+				}
+				results <- s.validateItem(ctx, route, item, opts)
+			}
+		}()
+	}
+
+	go func() {
+		for _, item := range items {
+			queue <- item
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+func (s *server) validateItem(ctx context.Context, route string, item batchItem, opts ValidationOptions) BatchResult {
+	result := BatchResult{ID: item.ID}
+
+	if item.URL == "" {
+		resp, err := ValidateJSON(item.Document, opts)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		metrics.Validation(route, resp.Valid)
+		result.Valid = resp.Valid
+		result.JSON = &resp
+		return result
+	}
+
+	u, err := url.ParseRequestURI(item.URL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	resp, err := s.checkURL(ctx, u, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// the endpoint wasn't unreachable, the fetch was aborted
+	if !resp.Reachable && ctx.Err() != nil {
+		result.Error = errBatchAborted(ctx)
+		return result
+	}
+	recordChecks(route, resp)
+	result.Valid = resp.Valid
+	result.URL = &resp
+	return result
+}
+
+// errBatchAborted describes why an item wasn't validated
+func errBatchAborted(ctx context.Context) string {
+	if ctx.Err() == context.DeadlineExceeded {
+		return "the batch took too long, the item was aborted"
+	}
+	return "the batch was aborted"
+}
@@ -0,0 +1,193 @@
+package v2
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"goji.io"
+	"goji.io/pat"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func batchBody(base string) string {
+	return `{"items": [
+		{"id": "endpoint", "url": "` + base + `/status.json"},
+		{"id": "missing", "url": "` + base + `/missing.json"},
+		{"document": ` + validSpace + `},
+		{"id": "inline", "document": ` + invalidSpace + `}
+	]}`
+}
+
+func TestValidateBatch(t *testing.T) {
+	ts, _ := newEndpointServer()
+	defer ts.Close()
+
+	rr := forgeRequest(t, testServer.validateBatch, "POST", "/v2/validateBatch", strings.NewReader(batchBody(ts.URL)))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v (%s)",
+			status, http.StatusOK, rr.Body.String())
+	}
+
+	resp := BatchResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Valid || len(resp.Results) != 4 {
+		t.Fatalf("handler returned wrong response: %+v", resp)
+	}
+	if r := resp.Results["endpoint"]; !r.Valid || r.URL == nil || !r.URL.Reachable {
+		t.Errorf("wrong result for endpoint: %+v", r)
+	}
+	if r := resp.Results["missing"]; r.Valid || r.URL == nil || r.URL.Reachable {
+		t.Errorf("wrong result for missing: %+v", r)
+	}
+	if r := resp.Results["2"]; !r.Valid || r.JSON == nil {
+		t.Errorf("wrong result for the item without id: %+v", r)
+	}
+	if r := resp.Results["inline"]; r.Valid || r.JSON == nil || len(r.JSON.SchemaErrors) == 0 {
+		t.Errorf("wrong result for inline: %+v", r)
+	}
+}
+
+func TestValidateBatchStream(t *testing.T) {
+	ts, _ := newEndpointServer()
+	defer ts.Close()
+
+	rr := forgeRequest(t, testServer.validateBatch, "POST", "/v2/validateBatch?format=ndjson", strings.NewReader(batchBody(ts.URL)))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != ContentTypeNDJSON {
+		t.Errorf("handler returned wrong content type: %v", contentType)
+	}
+
+	ids := map[string]bool{}
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var result BatchResult
+		err := json.Unmarshal(scanner.Bytes(), &result)
+		if err != nil {
+			t.Fatalf("line is not a result: %s", scanner.Text())
+		}
+		ids[result.ID] = true
+	}
+	if len(ids) != 4 {
+		t.Errorf("handler returned wrong results: %v", ids)
+	}
+}
+
+func TestValidateBatchInvalid(t *testing.T) {
+	tooMany := `{"items": [` + strings.Repeat(`{"url": "https://example.com/"},`, testServer.cfg.BatchMaxItems) + `{"url": "https://example.com/"}]}`
+
+	tests := map[string]string{
+		"no items":      `{"items": []}`,
+		"too many":      tooMany,
+		"duplicate ids": `{"items": [{"id": "a", "url": "https://example.com/"}, {"id": "a", "document": {}}]}`,
+		"url and doc":   `{"items": [{"url": "https://example.com/", "document": {}}]}`,
+		"neither":       `{"items": [{"id": "a"}]}`,
+		"version":       `{"items": [{"document": {}}], "versions": ["99"]}`,
+	}
+
+	for name, body := range tests {
+		rr := forgeRequest(t, testServer.validateBatch, "POST", "/v2/validateBatch", strings.NewReader(body))
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v",
+				name, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestValidateBatchDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second * 5):
+		}
+	}))
+	defer ts.Close()
+
+	s := &server{cfg: testConfig()}
+	s.cfg.WriteTimeout = time.Millisecond * 400
+
+	body := `{"items": [
+		{"id": "slow", "url": "` + ts.URL + `/status.json"},
+		{"id": "inline", "document": ` + validSpace + `}
+	]}`
+	start := time.Now()
+	rr := forgeRequest(t, s.validateBatch, "POST", "/v2/validateBatch", strings.NewReader(body))
+
+	if elapsed := time.Since(start); elapsed >= s.cfg.WriteTimeout {
+		t.Errorf("batch took %v, longer than the write timeout", elapsed)
+	}
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := BatchResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := resp.Results["slow"]; r.Valid || r.Error == "" {
+		t.Errorf("wrong result for slow: %+v", r)
+	}
+	if r := resp.Results["inline"]; !r.Valid {
+		t.Errorf("wrong result for inline: %+v", r)
+	}
+}
+
+func TestValidateBatchTooManyURLs(t *testing.T) {
+	s := &server{cfg: testConfig()}
+	s.cfg.WriteTimeout = time.Second * 25
+	s.cfg.FetchTimeout = time.Second * 10
+	s.cfg.BatchParallelism = 2
+
+	// 20s are left to fetch, which is two rounds of two endpoints
+	if n := maxBatchURLs(s.cfg); n != 4 {
+		t.Fatalf("wrong maximum: got %v want %v", n, 4)
+	}
+
+	body := `{"items": [` + strings.Repeat(`{"url": "https://example.com/"},`, 4) + `{"url": "https://example.com/"}]}`
+
+	rr := forgeRequest(t, s.validateBatch, "POST", "/v2/validateBatch", strings.NewReader(body))
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func TestValidateBatchRateLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit = 0.001
+	cfg.RateBurst = 3
+	v2, stop := GetSubMux(context.Background(), cfg, nil)
+	defer stop()
+	mux := goji.NewMux()
+	mux.Handle(pat.New("/v2/*"), v2)
+
+	// four endpoints cost four tokens, one more than the burst
+	body := `{"items": [` + strings.Repeat(`{"url": "https://example.com/"},`, 3) + `{"url": "https://example.com/"}]}`
+	rr := forgeRequest(t, mux.ServeHTTP, "POST", "/v2/validateBatch", strings.NewReader(body))
+	if status := rr.Code; status != http.StatusTooManyRequests {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusTooManyRequests)
+	}
+
+	// inline documents only cost the request
+	body = `{"items": [{"document": ` + validSpace + `}, {"document": ` + validSpace + `}]}`
+	rr = forgeRequest(t, mux.ServeHTTP, "POST", "/v2/validateBatch", strings.NewReader(body))
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+}
@@ -0,0 +1,44 @@
+package v2
+
+import (
+	"github.com/spaceapi/validator/internal/httpbody"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateJsonBodyTooLarge(t *testing.T) {
+	rr := forgeRequest(t, httpbody.MaxBytes(http.HandlerFunc(testServer.validateJSON), 64).ServeHTTP, "POST", "/v2/validateJSON", strings.NewReader(validSpace))
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestValidateUrlTrailingData(t *testing.T) {
+	rr := forgeValidateURLRequest(t, strings.NewReader(`{ "url": "http://localhost:666/" } { "url": "http://localhost:667/" }`))
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func TestValidateUrlFetchedBodyTooLarge(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(validSpace))
+		}))
+	defer ts.Close()
+
+	s := &server{cfg: testConfig()}
+	s.cfg.MaxFetchBodySize = 64
+
+	resp := postValidateURL(t, s, ts.URL)
+
+	if resp.BodyTooLarge != true || resp.Valid != false || resp.Reachable != true {
+		t.Errorf("wrong response: got %+v", resp)
+	}
+}
@@ -0,0 +1,169 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// thirdPartyOrigin stands in for any site embedding the endpoint, so
+// endpoints allowing only the validator itself are noticed
+const thirdPartyOrigin = "https://dashboard.example.org"
+
+// preflightHeader is requested in the preflight. Dashboards commonly send it
+// to bypass caches, which makes browsers preflight the request.
+const preflightHeader = "cache-control"
+
+// CORSCheck is the result of the CORS checks for a single origin
+type CORSCheck struct {
+	Origin string `json:"origin"`
+	// Simple is true if a plain GET from the origin may read the response
+	Simple bool `json:"simple"`
+	// Preflight is true if the endpoint answers a preflight for a GET with
+	// additional headers
+	Preflight    bool     `json:"preflight"`
+	AllowOrigin  string   `json:"allowOrigin,omitempty"`
+	AllowMethods []string `json:"allowMethods,omitempty"`
+	AllowHeaders []string `json:"allowHeaders,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// checkCORS sends a GET and a preflight from the validator origin and a third
+// party origin to the endpoint
+func (s *server) checkCORS(ctx context.Context, u *url.URL, skipVerify bool) []CORSCheck {
+	client := s.newClient(skipVerify)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		// browsers don't follow redirects of preflights, and u already is
+		// the final URL of the endpoint
+		return http.ErrUseLastResponse
+	}
+	defer client.CloseIdleConnections()
+
+	var checks []CORSCheck
+	for _, origin := range []string{s.cfg.Origin, thirdPartyOrigin} {
+		check := CORSCheck{Origin: origin}
+		checkSimple(ctx, client, u, &check)
+		checkPreflight(ctx, client, u, &check)
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func checkSimple(ctx context.Context, client *http.Client, u *url.URL, check *CORSCheck) {
+	header, err := corsRequest(ctx, client, http.MethodGet, u, check.Origin, nil)
+	if err != nil {
+		check.Errors = append(check.Errors, "GET: "+err.Error())
+		return
+	}
+
+	check.AllowOrigin = header.Get("Access-Control-Allow-Origin")
+	if !allowsOrigin(check.AllowOrigin, check.Origin) {
+		check.Errors = append(check.Errors, fmt.Sprintf(
+			"GET: Access-Control-Allow-Origin %q doesn't allow %s", check.AllowOrigin, check.Origin,
+		))
+		return
+	}
+	check.Simple = true
+}
+
+func checkPreflight(ctx context.Context, client *http.Client, u *url.URL, check *CORSCheck) {
+	header, err := corsRequest(ctx, client, http.MethodOptions, u, check.Origin, http.Header{
+		"Access-Control-Request-Method":  {http.MethodGet},
+		"Access-Control-Request-Headers": {preflightHeader},
+	})
+	if err != nil {
+		check.Errors = append(check.Errors, "preflight: "+err.Error())
+		return
+	}
+
+	check.AllowMethods = headerList(header, "Access-Control-Allow-Methods")
+	check.AllowHeaders = headerList(header, "Access-Control-Allow-Headers")
+
+	var failures []string
+	if acao := header.Get("Access-Control-Allow-Origin"); !allowsOrigin(acao, check.Origin) {
+		failures = append(failures, fmt.Sprintf("Access-Control-Allow-Origin %q doesn't allow %s", acao, check.Origin))
+	}
+	// GET is a safelisted method, it only fails if other methods are listed
+	if len(check.AllowMethods) > 0 && !contains(check.AllowMethods, http.MethodGet) {
+		failures = append(failures, "Access-Control-Allow-Methods doesn't include GET")
+	}
+	if !contains(check.AllowHeaders, preflightHeader) {
+		failures = append(failures, "Access-Control-Allow-Headers doesn't include "+preflightHeader)
+	}
+
+	for _, failure := range failures {
+		check.Errors = append(check.Errors, "preflight: "+failure)
+	}
+	check.Preflight = len(failures) == 0
+}
+
+// corsRequest sends a request from origin and returns the response header. A
+// response status other than 2xx is an error.
+func corsRequest(ctx context.Context, client *http.Client, method string, u *url.URL, origin string, header http.Header) (http.Header, error) {
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Origin", origin)
+
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, unwrapURLError(err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, 1<<16))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("status %d", response.StatusCode)
+	}
+
+	return response.Header, nil
+}
+
+func allowsOrigin(allowOrigin, origin string) bool {
+	return allowOrigin == "*" || allowOrigin == origin
+}
+
+// headerList splits the comma separated values of a header
+func headerList(header http.Header, name string) []string {
+	var list []string
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// contains reports whether list contains value or the wildcard, ignoring case
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == "*" || strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// finalURL returns the URL the endpoint was served from after redirects
+func finalURL(u *url.URL, redirects *RedirectReport) *url.URL {
+	if redirects == nil || len(redirects.Hops) == 0 {
+		return u
+	}
+	final, err := url.Parse(redirects.Hops[len(redirects.Hops)-1].URL)
+	if err != nil {
+		return u
+	}
+	return final
+}
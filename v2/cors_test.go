@@ -0,0 +1,170 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// checkCORSDeep validates target in deep mode, the document served must not
+// link to anything
+func checkCORSDeep(t *testing.T, target string) URLValidationResponse {
+	u, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := testServer.checkURL(context.Background(), u, ValidationOptions{Deep: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestValidateUrlCorsPreflight(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Cache-Control")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(invalidSpace))
+		}))
+	defer ts.Close()
+
+	resp := checkCORSDeep(t, ts.URL)
+
+	if resp.Cors != true {
+		t.Errorf("handler returned wrong cors: got %v want %v", resp.Cors, true)
+	}
+
+	if len(resp.CORSChecks) != 2 {
+		t.Fatalf("wrong number of cors checks: %+v", resp.CORSChecks)
+	}
+	for _, check := range resp.CORSChecks {
+		if !check.Simple || !check.Preflight || len(check.Errors) > 0 {
+			t.Errorf("cors check failed: %+v", check)
+		}
+	}
+	if resp.CORSChecks[1].Origin != thirdPartyOrigin {
+		t.Errorf("wrong origin: got %v want %v", resp.CORSChecks[1].Origin, thirdPartyOrigin)
+	}
+}
+
+func TestValidateUrlCorsValidatorOriginOnly(t *testing.T) {
+	origin := testServer.cfg.Origin
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Origin") == origin {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			_, _ = w.Write([]byte(invalidSpace))
+		}))
+	defer ts.Close()
+
+	resp := checkCORSDeep(t, ts.URL)
+
+	// cors only covers the validator origin, the third party origin is
+	// reported in the checks
+	if resp.Cors != true {
+		t.Errorf("handler returned wrong cors: got %v want %v", resp.Cors, true)
+	}
+
+	if len(resp.CORSChecks) != 2 {
+		t.Fatalf("wrong number of cors checks: %+v", resp.CORSChecks)
+	}
+	if !resp.CORSChecks[0].Simple {
+		t.Errorf("validator origin rejected: %+v", resp.CORSChecks[0])
+	}
+	if resp.CORSChecks[1].Simple {
+		t.Errorf("third party origin accepted: %+v", resp.CORSChecks[1])
+	}
+}
+
+func TestValidateUrlCorsPreflightRejected(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			_, _ = w.Write([]byte(invalidSpace))
+		}))
+	defer ts.Close()
+
+	resp := checkCORSDeep(t, ts.URL)
+
+	if resp.Cors != true {
+		t.Errorf("handler returned wrong cors: got %v want %v", resp.Cors, true)
+	}
+
+	for _, check := range resp.CORSChecks {
+		if check.Preflight || len(check.Errors) != 1 {
+			t.Errorf("preflight not rejected: %+v", check)
+		}
+	}
+}
+
+func TestValidateUrlCorsNotDeep(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			_, _ = w.Write([]byte(invalidSpace))
+		}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.Cors != true || resp.CORSChecks != nil {
+		t.Errorf("wrong cors result: got %v %+v", resp.Cors, resp.CORSChecks)
+	}
+	if requests != 1 {
+		t.Errorf("wrong number of requests: got %v want %v", requests, 1)
+	}
+}
+
+func TestCheckPreflightHeaders(t *testing.T) {
+	tests := []struct {
+		methods string
+		headers string
+		ok      bool
+	}{
+		{"", "cache-control", true},
+		{"GET", "*", true},
+		{"*", "Cache-Control, X-Custom", true},
+		{"POST, PUT", "cache-control", false},
+		{"GET", "", false},
+		{"GET", "x-custom", false},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewServer(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+				if test.methods != "" {
+					w.Header().Set("Access-Control-Allow-Methods", test.methods)
+				}
+				if test.headers != "" {
+					w.Header().Set("Access-Control-Allow-Headers", test.headers)
+				}
+			}))
+
+		u, _ := url.Parse(ts.URL)
+		check := CORSCheck{Origin: thirdPartyOrigin}
+		checkPreflight(context.Background(), ts.Client(), u, &check)
+		ts.Close()
+
+		if check.Preflight != test.ok {
+			t.Errorf("wrong preflight result for methods %q and headers %q: got %v want %v",
+				test.methods, test.headers, check.Preflight, test.ok)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/spaceapi/validator/config"
+	"github.com/spaceapi/validator/internal/httpbody"
+	"github.com/spaceapi/validator/metrics"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Results of the spaces of a directory
+const (
+	ResultValid       = "valid"
+	ResultInvalid     = "invalid"
+	ResultUnreachable = "unreachable"
+	ResultError       = "error"
+)
+
+type directoryRequest struct {
+	URL       string            `json:"url"`
+	Directory map[string]string `json:"directory"`
+	Versions  []string          `json:"versions"`
+	Callback  string            `json:"callback"`
+}
+
+// DirectoryReport summarizes the validation of the endpoints listed in a
+// SpaceAPI directory. Results counts the spaces by result, Versions and
+// FailedChecks count them by checked version and failed check, the most
+// frequent first.
+type DirectoryReport struct {
+	Total        int            `json:"total"`
+	Results      map[string]int `json:"results"`
+	Versions     []Count        `json:"versions"`
+	FailedChecks []Count        `json:"failedChecks"`
+	Spaces       []SpaceReport  `json:"spaces"`
+}
+
+// Count is the number of spaces using a version or failing a check
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// SpaceReport is the result of validating the endpoint of a space
+type SpaceReport struct {
+	Space        string   `json:"space"`
+	URL          string   `json:"url"`
+	Result       string   `json:"result"`
+	Versions     []string `json:"versions,omitempty"`
+	FailedChecks []string `json:"failedChecks,omitempty"`
+	SchemaErrors int      `json:"schemaErrors"`
+	Message      string   `json:"message,omitempty"`
+}
+
+func (s *server) validateDirectory(writer http.ResponseWriter, request *http.Request) {
+	if request.Body == nil {
+		http.Error(writer, "body can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	var dirReq directoryRequest
+	err := httpbody.DecodeStrict(request.Body, &dirReq)
+	if err != nil {
+		httpbody.Error(writer, err, http.StatusBadRequest)
+		return
+	}
+	if (dirReq.URL == "") == (dirReq.Directory == nil) {
+		http.Error(writer, "either url or directory must be given", http.StatusBadRequest)
+		return
+	}
+	if err := checkCallback(dirReq.Callback); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schemas := s.schemas.Current()
+	versions, err := schemas.ParseVersions(dirReq.Versions)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	directory := dirReq.Directory
+	if dirReq.URL != "" {
+		u, err := url.ParseRequestURI(dirReq.URL)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		directory, err = s.fetchDirectory(request.Context(), u)
+		if err != nil {
+			http.Error(writer, "directory can't be fetched: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	if len(directory) > s.cfg.DirectoryMaxSpaces {
+		http.Error(writer, fmt.Sprintf("directory has %d spaces, at most %d are allowed", len(directory), s.cfg.DirectoryMaxSpaces), http.StatusBadRequest)
+		return
+	}
+
+	// the request itself was charged one token, every further endpoint to
+	// fetch costs another one
+	if s.directoryLimit != nil && !s.directoryLimit.Take(writer, request, len(directory)-1) {
+		return
+	}
+
+	// validating hundreds of endpoints takes longer than the WriteTimeout,
+	// so the report is built by a job
+	s.queueJob(writer, &Job{
+		URL:       dirReq.URL,
+		Callback:  dirReq.Callback,
+		directory: directory,
+		opts:      ValidationOptions{Versions: versions, Schemas: schemas},
+	})
+}
+
+// ValidateDirectory validates the endpoints of a SpaceAPI directory like
+// /v2/validateURL does and builds the report returned by
+// /v2/validateDirectory
+func ValidateDirectory(ctx context.Context, cfg config.Config, directory map[string]string, opts ValidationOptions) DirectoryReport {
+	return newServer(cfg, nil).checkDirectory(ctx, metrics.RouteV2Directory, directory, opts)
+}
+
+// FetchDirectory fetches a SpaceAPI directory with the same restrictions as
+// endpoints
+func FetchDirectory(ctx context.Context, cfg config.Config, u *url.URL) (map[string]string, error) {
+	return newServer(cfg, nil).fetchDirectory(ctx, u)
+}
+
+// ParseDirectory decodes a SpaceAPI directory, which maps space names to
+// endpoint URLs
+func ParseDirectory(body []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	err := json.Unmarshal(body, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("directory is not a JSON object")
+	}
+
+	directory := map[string]string{}
+	for space, value := range raw {
+		endpoint, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("endpoint of %q is not a string", space)
+		}
+		directory[space] = endpoint
+	}
+	return directory, nil
+}
+
+func (s *server) fetchDirectory(ctx context.Context, u *url.URL) (map[string]string, error) {
+	client := s.newClient(false)
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Origin", s.cfg.Origin)
+
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, unwrapURLError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("status %s", response.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, s.cfg.MaxFetchBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > s.cfg.MaxFetchBodySize {
+		return nil, httpbody.ErrTooLarge
+	}
+	return ParseDirectory(body)
+}
+
+// checkDirectory validates the endpoints of a directory in parallel and
+// aggregates the results
+func (s *server) checkDirectory(ctx context.Context, route string, directory map[string]string, opts ValidationOptions) DirectoryReport {
+	var spaces []string
+	for space := range directory {
+		spaces = append(spaces, space)
+	}
+	sort.Strings(spaces)
+
+	items := make([]batchItem, len(spaces))
+	for i, space := range spaces {
+		items[i] = batchItem{ID: space, URL: directory[space]}
+	}
+
+	results := map[string]BatchResult{}
+	if len(items) > 0 {
+		for result := range s.runBatch(ctx, route, items, opts) {
+			results[result.ID] = result
+		}
+	}
+
+	report := DirectoryReport{Total: len(spaces), Results: map[string]int{}, Spaces: []SpaceReport{}}
+	versions := map[string]int{}
+	checks := map[string]int{}
+	for _, space := range spaces {
+		spaceReport := spaceResult(space, directory[space], results[space])
+		report.Spaces = append(report.Spaces, spaceReport)
+		report.Results[spaceReport.Result]++
+		for _, version := range spaceReport.Versions {
+			versions[version]++
+		}
+		for _, check := range spaceReport.FailedChecks {
+			checks[check]++
+		}
+	}
+	report.Versions = sortedCounts(versions)
+	report.FailedChecks = sortedCounts(checks)
+
+	return report
+}
+
+func spaceResult(space, endpoint string, result BatchResult) SpaceReport {
+	report := SpaceReport{Space: space, URL: endpoint}
+	if result.URL == nil {
+		report.Result = ResultError
+		report.Message = result.Error
+		return report
+	}
+
+	valRes := result.URL
+	report.Versions = valRes.CheckedVersions
+	report.FailedChecks = failedChecks(*valRes)
+	report.SchemaErrors = len(valRes.SchemaErrors)
+	report.Message = strings.SplitN(strings.TrimSpace(valRes.Message), "\n", 2)[0]
+
+	switch {
+	case !valRes.Reachable:
+		report.Result = ResultUnreachable
+		if valRes.Reachability != nil && report.Message == "" {
+			report.Message = valRes.Reachability.Error
+		}
+	case valRes.Valid:
+		report.Result = ResultValid
+	default:
+		report.Result = ResultInvalid
+	}
+	return report
+}
+
+// sortedCounts returns the counts in descending order, ties ordered by name
+func sortedCounts(counts map[string]int) []Count {
+	sorted := []Count{}
+	for name, count := range counts {
+		sorted = append(sorted, Count{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
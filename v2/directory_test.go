@@ -0,0 +1,157 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"goji.io"
+	"goji.io/pat"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runDirectoryJob posts body to /v2/validateDirectory and waits for the
+// report of the job
+func runDirectoryJob(t *testing.T, body string) DirectoryReport {
+	s := &server{cfg: testConfig()}
+	s.jobs = newJobQueue(context.Background(), 1, 1, time.Hour, s.runJob)
+	defer s.jobs.stop()
+
+	rr := forgeRequest(t, s.validateDirectory, "POST", "/v2/validateDirectory", strings.NewReader(body))
+	if status := rr.Code; status != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v (%s)",
+			status, http.StatusAccepted, rr.Body.String())
+	}
+
+	var job Job
+	err := json.NewDecoder(rr.Body).Decode(&job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second * 10)
+	for job.Status != jobDone && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+		job, _ = s.jobs.get(job.ID)
+	}
+	if job.Status != jobDone || job.Directory == nil {
+		t.Fatalf("job wasn't done: %+v", job)
+	}
+	return *job.Directory
+}
+
+func TestValidateDirectory(t *testing.T) {
+	ts, _ := newEndpointServer()
+	defer ts.Close()
+
+	report := runDirectoryJob(t, `{"directory": {
+		"Valid Space": "`+ts.URL+`/status.json",
+		"Gone Space": "`+ts.URL+`/missing.json",
+		"Broken Space": "not an url"
+	}}`)
+
+	if report.Total != 3 || report.Results[ResultValid] != 1 || report.Results[ResultUnreachable] != 1 || report.Results[ResultError] != 1 {
+		t.Errorf("wrong results: %v", report.Results)
+	}
+	if len(report.Versions) != 1 || report.Versions[0] != (Count{Name: "13", Count: 1}) {
+		t.Errorf("wrong versions: %v", report.Versions)
+	}
+
+	checks := map[string]int{}
+	for _, check := range report.FailedChecks {
+		checks[check.Name] = check.Count
+	}
+	if checks["reachable"] != 1 {
+		t.Errorf("wrong failed checks: %v", report.FailedChecks)
+	}
+
+	var spaces []string
+	for _, space := range report.Spaces {
+		spaces = append(spaces, space.Space+"="+space.Result)
+	}
+	if got := strings.Join(spaces, " "); got != "Broken Space=error Gone Space=unreachable Valid Space=valid" {
+		t.Errorf("wrong spaces: %v", got)
+	}
+}
+
+func TestValidateDirectoryURL(t *testing.T) {
+	ts, _ := newEndpointServer()
+	defer ts.Close()
+	directory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Valid Space": "` + ts.URL + `/status.json"}`))
+	}))
+	defer directory.Close()
+
+	report := runDirectoryJob(t, `{"url": "`+directory.URL+`"}`)
+
+	if report.Total != 1 || report.Results[ResultValid] != 1 {
+		t.Errorf("wrong results: %v", report.Results)
+	}
+}
+
+func TestValidateDirectoryInvalid(t *testing.T) {
+	tests := map[string]string{
+		"neither":     `{}`,
+		"both":        `{"url": "https://example.com/", "directory": {}}`,
+		"no string":   `{"directory": {"space": 1}}`,
+		"bad version": `{"directory": {}, "versions": ["99"]}`,
+		"callback":    `{"directory": {}, "callback": "ftp://example.com/"}`,
+	}
+
+	for name, body := range tests {
+		rr := forgeRequest(t, testServer.validateDirectory, "POST", "/v2/validateDirectory", strings.NewReader(body))
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v",
+				name, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestValidateDirectoryRateLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit = 0.001
+	cfg.RateBurst = 2
+	v2, stop := GetSubMux(context.Background(), cfg, nil)
+	defer stop()
+	mux := goji.NewMux()
+	mux.Handle(pat.New("/v2/*"), v2)
+
+	// three endpoints cost three tokens, one more than the burst
+	body := `{"directory": {"a": "https://example.com/a", "b": "https://example.com/b", "c": "https://example.com/c"}}`
+	rr := forgeRequest(t, mux.ServeHTTP, "POST", "/v2/validateDirectory", strings.NewReader(body))
+	if status := rr.Code; status != http.StatusTooManyRequests {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusTooManyRequests)
+	}
+}
+
+func TestDirectoryTimeout(t *testing.T) {
+	s := &server{cfg: testConfig()}
+	s.cfg.BatchParallelism = 4
+	s.cfg.FetchTimeout = time.Second * 10
+
+	tests := map[int]time.Duration{
+		1:   jobTimeout,
+		500: time.Second * 1250,
+	}
+	for spaces, want := range tests {
+		if got := s.directoryTimeout(spaces); got != want {
+			t.Errorf("wrong timeout for %d spaces: got %v want %v", spaces, got, want)
+		}
+	}
+}
+
+func TestParseDirectory(t *testing.T) {
+	directory, err := ParseDirectory([]byte(`{"space": "https://example.com/status.json"}`))
+	if err != nil || directory["space"] != "https://example.com/status.json" {
+		t.Errorf("wrong directory: %v %v", directory, err)
+	}
+
+	for _, body := range []string{`[]`, `null`, `{"space": null}`, `foo`} {
+		if _, err := ParseDirectory([]byte(body)); err == nil {
+			t.Errorf("no error for %s", body)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spaceapi/validator/lint"
+	"github.com/spaceapi/validator/schema"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is an operation of an RFC 6902 JSON patch
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// fixIt is the suggested fix of a schema error. op is nil if the fix can't
+// be determined mechanically.
+type fixIt struct {
+	suggestion string
+	op         *PatchOperation
+}
+
+// suggestFix returns a fix for the schema error described by detail at
+// pointer, which is the pointer of the invalid value or, for missing
+// properties, of the object missing it
+func suggestFix(doc lint.Document, pointer string, detail schema.ErrorDetail) (fixIt, bool) {
+	switch detail.Type {
+	case "invalid_type":
+		value, ok := doc.Lookup(pointer)
+		if !ok {
+			return fixIt{}, false
+		}
+		return convertType(pointer, value, expectedTypes(detail.Details["expected"]))
+	case "required":
+		property, _ := detail.Details["property"].(string)
+		value, _ := doc.Lookup(pointer)
+		object, ok := value.(map[string]interface{})
+		if property == "" || !ok {
+			return fixIt{}, false
+		}
+
+		path := pointer + "/" + escapePointer(property)
+		if key, ok := misspelledKey(object, property); ok {
+			return fixIt{
+				suggestion: fmt.Sprintf("rename %q to %q", key, property),
+				op:         &PatchOperation{Op: "move", From: pointer + "/" + escapePointer(key), Path: path},
+			}, true
+		}
+		return fixIt{suggestion: fmt.Sprintf("add the required field %q", property)}, true
+	case schema.ErrorTypeUnsupportedVersion:
+		return fixIt{suggestion: "remove the version from api_compatibility or validate against a supported version"}, true
+	}
+	return fixIt{}, false
+}
+
+// expectedTypes splits the expected types of an invalid_type error, which
+// are formatted like string or [boolean,null]
+func expectedTypes(expected interface{}) []string {
+	list := strings.Trim(fmt.Sprintf("%v", expected), "[]")
+	return strings.Split(list, ",")
+}
+
+// convertType converts value into the first of the expected types it can be
+// converted into without loss, like the number 0.13 into the string "0.13"
+func convertType(pointer string, value interface{}, expected []string) (fixIt, bool) {
+	for _, kind := range expected {
+		converted, ok := convertValue(value, kind)
+		if !ok {
+			continue
+		}
+
+		from, _ := json.Marshal(value)
+		to, err := json.Marshal(converted)
+		if err != nil {
+			continue
+		}
+		return fixIt{
+			suggestion: fmt.Sprintf("replace %s by %s", from, to),
+			op:         &PatchOperation{Op: "replace", Path: pointer, Value: to},
+		}, true
+	}
+	return fixIt{}, false
+}
+
+func convertValue(value interface{}, kind string) (interface{}, bool) {
+	switch kind {
+	case "string":
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	case "number", "integer":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || (kind == "integer" && number != float64(int64(number))) {
+			return nil, false
+		}
+		return number, true
+	case "boolean":
+		switch v := value.(type) {
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, true
+			}
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, true
+			}
+		}
+	case "array":
+		switch value.(type) {
+		case string, float64, bool, map[string]interface{}:
+			return []interface{}{value}, true
+		}
+	}
+	return nil, false
+}
+
+// misspelledKey returns the key of object which is a misspelling of property
+// by a single edit. Keys of extensions and ambiguous matches are ignored, as
+// a wrong rename is worse than none.
+func misspelledKey(object map[string]interface{}, property string) (string, bool) {
+	const maxDistance = 1
+
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	best, bestDistance, ambiguous := "", maxDistance+1, false
+	for _, key := range keys {
+		if strings.HasPrefix(key, "ext_") {
+			continue
+		}
+		d := editDistance(strings.ToLower(key), strings.ToLower(property))
+		switch {
+		case d < bestDistance:
+			best, bestDistance, ambiguous = key, d, false
+		case d == bestDistance:
+			ambiguous = true
+		}
+	}
+	return best, best != "" && !ambiguous
+}
+
+// editDistance is the Damerau-Levenshtein distance of a and b, counting a
+// transposition of two adjacent characters as one edit
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// patchBuilder collects the operations of a JSON patch. An operation touching
+// a path another operation already touches is dropped, as errors are
+// reported once per validated version.
+type patchBuilder struct {
+	patch   []PatchOperation
+	touched map[string]bool
+}
+
+func (b *patchBuilder) add(op PatchOperation) {
+	if b.touched == nil {
+		b.touched = map[string]bool{}
+	}
+	if b.touched[op.Path] || (op.From != "" && b.touched[op.From]) {
+		return
+	}
+	b.touched[op.Path] = true
+	if op.From != "" {
+		b.touched[op.From] = true
+	}
+	b.patch = append(b.patch, op)
+}
@@ -0,0 +1,171 @@
+package v2
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// applyPatch applies the replace and move operations of a JSON patch to
+// members of objects
+func applyPatch(t *testing.T, document []byte, patch []PatchOperation) []byte {
+	var doc map[string]interface{}
+	err := json.Unmarshal(document, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := func(pointer string) (map[string]interface{}, string) {
+		segments := strings.Split(pointer, "/")[1:]
+		object := doc
+		for _, segment := range segments[:len(segments)-1] {
+			object = object[segment].(map[string]interface{})
+		}
+		return object, segments[len(segments)-1]
+	}
+
+	for _, op := range patch {
+		object, key := parent(op.Path)
+		switch op.Op {
+		case "replace":
+			var value interface{}
+			_ = json.Unmarshal(op.Value, &value)
+			object[key] = value
+		case "move":
+			from, fromKey := parent(op.From)
+			object[key] = from[fromKey]
+			delete(from, fromKey)
+		default:
+			t.Fatalf("unexpected operation %v", op.Op)
+		}
+	}
+
+	patched, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return patched
+}
+
+func TestFixItWrongType(t *testing.T) {
+	resp, err := ValidateJSON([]byte(invalidSpaceApiVersion), ValidationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, schemaError := range resp.SchemaErrors {
+		if schemaError.Pointer == "/api" && schemaError.Suggestion != `replace 0.13 by "0.13"` {
+			t.Errorf("wrong suggestion: %v", schemaError.Suggestion)
+		}
+	}
+
+	want := []PatchOperation{{Op: "replace", Path: "/api", Value: json.RawMessage(`"0.13"`)}}
+	if !reflect.DeepEqual(resp.Patch, want) {
+		t.Fatalf("wrong patch: %+v", resp.Patch)
+	}
+
+	patched, err := ValidateJSON(applyPatch(t, []byte(invalidSpaceApiVersion), resp.Patch), ValidationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !patched.Valid {
+		t.Errorf("patched document is invalid: %v", patched.SchemaErrors)
+	}
+}
+
+func TestFixItMisspelledKey(t *testing.T) {
+	document := strings.Replace(validSpace, `"space":`, `"spcae":`, 1)
+	resp, err := ValidateJSON([]byte(document), ValidationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PatchOperation{{Op: "move", From: "/spcae", Path: "/space"}}
+	if !reflect.DeepEqual(resp.Patch, want) {
+		t.Fatalf("wrong patch: %+v", resp.Patch)
+	}
+	if resp.SchemaErrors[0].Suggestion != `rename "spcae" to "space"` {
+		t.Errorf("wrong suggestion: %v", resp.SchemaErrors[0].Suggestion)
+	}
+
+	patched, err := ValidateJSON(applyPatch(t, []byte(document), resp.Patch), ValidationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !patched.Valid {
+		t.Errorf("patched document is invalid: %v", patched.SchemaErrors)
+	}
+}
+
+func TestFixItMissingField(t *testing.T) {
+	resp, err := ValidateJSON([]byte(strings.Replace(validSpace, `"space":`, `"name":`, 1)), ValidationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Patch != nil {
+		t.Errorf("patch for a missing field: %+v", resp.Patch)
+	}
+	if resp.SchemaErrors[0].Suggestion != `add the required field "space"` {
+		t.Errorf("wrong suggestion: %v", resp.SchemaErrors[0].Suggestion)
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		kind  string
+		want  interface{}
+		ok    bool
+	}{
+		{0.13, "string", "0.13", true},
+		{true, "string", "true", true},
+		{"48.777", "number", 48.777, true},
+		{"5", "integer", 5.0, true},
+		{"5.5", "integer", nil, false},
+		{"five", "number", nil, false},
+		{"true", "boolean", true, true},
+		{0.0, "boolean", false, true},
+		{2.0, "boolean", nil, false},
+		{"14", "array", []interface{}{"14"}, true},
+		{nil, "array", nil, false},
+	}
+
+	for _, test := range tests {
+		got, ok := convertValue(test.value, test.kind)
+		if ok != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("convertValue(%#v, %v): got %#v %v want %#v %v", test.value, test.kind, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestMisspelledKey(t *testing.T) {
+	tests := []struct {
+		keys     []string
+		property string
+		want     string
+	}{
+		{[]string{"spcae", "logo"}, "space", "spcae"},
+		{[]string{"Space"}, "space", "Space"},
+		{[]string{"lgo"}, "logo", "lgo"},
+		{[]string{"lg"}, "logo", ""},
+		{[]string{"state"}, "space", ""},
+		{[]string{"ext_spac"}, "space", ""},
+		{[]string{"spac", "spaec"}, "space", ""},
+	}
+
+	for _, test := range tests {
+		object := map[string]interface{}{}
+		for _, key := range test.keys {
+			object[key] = "x"
+		}
+		got, ok := misspelledKey(object, test.property)
+		if !ok {
+			got = ""
+		}
+		if got != test.want {
+			t.Errorf("misspelledKey(%v, %v): got %q want %q", test.keys, test.property, got, test.want)
+		}
+	}
+}
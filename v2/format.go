@@ -0,0 +1,334 @@
+package v2
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/spaceapi/validator/lint"
+	"io"
+	"mime"
+	"strings"
+)
+
+// Output formats for validation results
+const (
+	FormatJSON   = "json"
+	FormatJUnit  = "junit"
+	FormatSARIF  = "sarif"
+	FormatGitHub = "github"
+)
+
+// Report is the validation result of a named document, e.g. a file
+type Report struct {
+	Name   string                 `json:"name"`
+	Result JSONValidationResponse `json:"result"`
+}
+
+var contentTypes = map[string]string{
+	FormatJSON:   "application/json",
+	FormatJUnit:  "application/xml",
+	FormatSARIF:  "application/sarif+json",
+	FormatGitHub: "text/plain; charset=utf-8",
+}
+
+var acceptedTypes = map[string]string{
+	"application/json":       FormatJSON,
+	"application/xml":        FormatJUnit,
+	"text/xml":               FormatJUnit,
+	"application/junit+xml":  FormatJUnit,
+	"application/sarif+json": FormatSARIF,
+}
+
+// ContentType returns the Content-Type header value of a format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// negotiateFormat picks the output format from the format query parameter or,
+// if it is not set, from the Accept header
+func negotiateFormat(query string, accept string) (string, error) {
+	if query != "" {
+		if _, ok := contentTypes[query]; !ok {
+			return "", fmt.Errorf("unknown format %q", query)
+		}
+		return query, nil
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if format, ok := acceptedTypes[mediaType]; ok {
+			return format, nil
+		}
+	}
+
+	return FormatJSON, nil
+}
+
+// WriteReports writes the reports in the given format. JSON output is a list
+// of the reports in the given order, so reports with the same name are kept.
+func WriteReports(w io.Writer, format string, reports []Report) error {
+	switch format {
+	case FormatJSON:
+		if reports == nil {
+			reports = []Report{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case FormatJUnit:
+		return writeJUnit(w, reports)
+	case FormatSARIF:
+		return writeSARIF(w, reports)
+	case FormatGitHub:
+		return writeGitHub(w, reports)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a test suite per report, containing a failing test case
+// per schema error or a single passing one if the document is valid
+func writeJUnit(w io.Writer, reports []Report) error {
+	suites := junitTestSuites{Name: "spaceapi-validator"}
+	for _, report := range reports {
+		suite := junitTestSuite{Name: report.Name}
+		for _, schemaError := range report.Result.SchemaErrors {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      schemaError.Field,
+				ClassName: report.Name,
+				Failure: &junitFailure{
+					Message: schemaError.Message,
+					Type:    "schema",
+					Text:    schemaError.String(),
+				},
+			})
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{Name: "schema", ClassName: report.Name})
+		}
+
+		suite.Tests = len(suite.Cases)
+		for _, testCase := range suite.Cases {
+			if testCase.Failure != nil {
+				suite.Failures++
+			}
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(suites)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifLevels maps lint severities to SARIF result levels
+var sarifLevels = map[string]string{
+	lint.SeverityWarning: "warning",
+	lint.SeverityInfo:    "note",
+}
+
+func sarifLocationOf(uri string, line, column int) sarifPhysicalLocation {
+	location := sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: uri},
+	}
+	if line > 0 {
+		location.Region = &sarifRegion{
+			StartLine:   line,
+			StartColumn: column,
+		}
+	}
+	return location
+}
+
+func writeSARIF(w io.Writer, reports []Report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "spaceapi-validator",
+			InformationURI: "https://github.com/SpaceApi/validator",
+			Rules: []sarifRule{{
+				ID:               "schema",
+				ShortDescription: sarifMessage{Text: "Document violates the SpaceAPI schema"},
+			}},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, rule := range lint.DefaultRules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               rule.ID,
+			ShortDescription: sarifMessage{Text: rule.Description},
+		})
+	}
+
+	for _, report := range reports {
+		for _, schemaError := range report.Result.SchemaErrors {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    "schema",
+				Level:     "error",
+				Message:   sarifMessage{Text: schemaError.Field + ": " + schemaError.Message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifLocationOf(report.Name, schemaError.Line, schemaError.Column)}},
+			})
+		}
+		for _, warning := range report.Result.Warnings {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    warning.Rule,
+				Level:     sarifLevels[warning.Severity],
+				Message:   sarifMessage{Text: warning.Message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifLocationOf(report.Name, warning.Line, warning.Column)}},
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// githubCommands maps lint severities to GitHub Actions workflow commands
+var githubCommands = map[string]string{
+	lint.SeverityWarning: "warning",
+	lint.SeverityInfo:    "notice",
+}
+
+// writeGitHub writes GitHub Actions workflow commands which show up as
+// annotations on the file
+func writeGitHub(w io.Writer, reports []Report) error {
+	for _, report := range reports {
+		for _, schemaError := range report.Result.SchemaErrors {
+			err := writeGitHubCommand(w, "error", report.Name, schemaError.Line, schemaError.Column, schemaError.Field, schemaError.Message)
+			if err != nil {
+				return err
+			}
+		}
+		for _, warning := range report.Result.Warnings {
+			err := writeGitHubCommand(w, githubCommands[warning.Severity], report.Name, warning.Line, warning.Column, warning.Rule, warning.Message)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeGitHubCommand(w io.Writer, command, file string, line, column int, title, message string) error {
+	var position string
+	if line > 0 {
+		position = fmt.Sprintf(",line=%d,col=%d", line, column)
+	}
+
+	_, err := fmt.Fprintf(w, "::%s file=%s%s,title=%s::%s\n",
+		command,
+		escapeProperty(file),
+		position,
+		escapeProperty(title),
+		escapeData(message),
+	)
+	return err
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
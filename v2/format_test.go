@@ -0,0 +1,190 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var invalidReport = Report{
+	Name: "site/spaceapi.json",
+	Result: JSONValidationResponse{
+		Valid: false,
+		SchemaErrors: []SchemaError{
+			{Field: "(root).location.lat", Message: "Invalid type. Expected: number, given: string"},
+			{Field: "(root)", Message: "space is required"},
+		},
+	},
+}
+
+var validReport = Report{
+	Name:   "other.json",
+	Result: JSONValidationResponse{Valid: true},
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		query  string
+		accept string
+		want   string
+	}{
+		{"", "", FormatJSON},
+		{"", "*/*", FormatJSON},
+		{"", "application/sarif+json", FormatSARIF},
+		{"", "text/html, application/xml;q=0.9", FormatJUnit},
+		{"github", "application/xml", FormatGitHub},
+	}
+
+	for _, c := range cases {
+		format, err := negotiateFormat(c.query, c.accept)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != c.want {
+			t.Errorf("wrong format for %q/%q: got %v want %v", c.query, c.accept, format, c.want)
+		}
+	}
+
+	_, err := negotiateFormat("yaml", "")
+	if err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteReports(&buf, FormatJSON, []Report{invalidReport, validReport, invalidReport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []Report
+	err = json.Unmarshal(buf.Bytes(), &reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, report := range reports {
+		names = append(names, report.Name)
+	}
+	want := []string{invalidReport.Name, validReport.Name, invalidReport.Name}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("wrong reports: got %v want %v", names, want)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteReports(&buf, FormatJUnit, []Report{invalidReport, validReport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitTestSuites
+	err = xml.Unmarshal(buf.Bytes(), &suites)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if suites.Tests != 3 || suites.Failures != 2 {
+		t.Errorf("wrong counts: got %v tests and %v failures want 3 and 2", suites.Tests, suites.Failures)
+	}
+
+	if len(suites.Suites) != 2 || suites.Suites[0].Name != invalidReport.Name {
+		t.Errorf("wrong test suites: %+v", suites.Suites)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteReports(&buf, FormatSARIF, []Report{invalidReport, validReport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	err = json.Unmarshal(buf.Bytes(), &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("wrong sarif log: %+v", log)
+	}
+
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("wrong number of results: got %v want %v", len(results), 2)
+	}
+
+	if uri := results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != invalidReport.Name {
+		t.Errorf("wrong artifact uri: got %v want %v", uri, invalidReport.Name)
+	}
+}
+
+func TestWriteGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteReports(&buf, FormatGitHub, []Report{invalidReport, validReport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrong number of annotations: got %v want %v", len(lines), 2)
+	}
+
+	want := "::error file=site/spaceapi.json,title=(root).location.lat::Invalid type. Expected: number, given: string"
+	if lines[0] != want {
+		t.Errorf("wrong annotation: got %v want %v", lines[0], want)
+	}
+}
+
+func TestValidateJsonFormatQuery(t *testing.T) {
+	rr := forgeRequest(t, testServer.validateJSON, "POST", "/v2/validateJSON?format=github&file=spaceapi.json", strings.NewReader(invalidSpace))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	if !strings.HasPrefix(rr.Body.String(), "::error file=spaceapi.json,") {
+		t.Errorf("handler returned wrong body: %v", rr.Body.String())
+	}
+}
+
+func TestValidateJsonFormatAccept(t *testing.T) {
+	req, err := http.NewRequest("POST", "/v2/validateJSON", strings.NewReader(invalidSpace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/sarif+json")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(testServer.validateJSON).ServeHTTP(rr, req)
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/sarif+json" {
+		t.Errorf("handler returned wrong content type: got %v want %v",
+			contentType, "application/sarif+json")
+	}
+
+	var log sarifLog
+	err = json.NewDecoder(rr.Body).Decode(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateJsonUnknownFormat(t *testing.T) {
+	rr := forgeRequest(t, testServer.validateJSON, "POST", "/v2/validateJSON?format=yaml", strings.NewReader(validSpace))
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
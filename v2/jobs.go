@@ -0,0 +1,417 @@
+package v2
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spaceapi/validator/internal/httpbody"
+	"github.com/spaceapi/validator/metrics"
+	"goji.io/pat"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// jobTimeout limits the duration of a job, including deep checks and the
+// callback. Directory jobs may take longer, see directoryTimeout.
+const jobTimeout = time.Minute * 5
+
+// queueFullRetry is sent in the Retry-After header when the queue is full
+const queueFullRetry = "10"
+
+var (
+	errQueueFull = errors.New("too many queued validation jobs, try again later")
+	errStopped   = errors.New("the validator is shutting down, try again later")
+)
+
+type jobRequest struct {
+	urlValidationRequest
+	Callback string `json:"callback"`
+}
+
+// Job is an asynchronous validation of an URL or of the endpoints of a
+// directory. URL is the one of the directory for directory jobs, it is empty
+// if the directory was sent inline.
+type Job struct {
+	ID            string                 `json:"id"`
+	Status        string                 `json:"status"`
+	URL           string                 `json:"url,omitempty"`
+	Callback      string                 `json:"callback,omitempty"`
+	Created       time.Time              `json:"created"`
+	Started       *time.Time             `json:"started,omitempty"`
+	Finished      *time.Time             `json:"finished,omitempty"`
+	Result        *URLValidationResponse `json:"result,omitempty"`
+	Directory     *DirectoryReport       `json:"directory,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	CallbackError string                 `json:"callbackError,omitempty"`
+
+	target    *url.URL
+	directory map[string]string
+	opts      ValidationOptions
+}
+
+// jobQueue runs jobs on a fixed number of workers. Jobs are rejected if the
+// queue is full, so load spikes can't start an unbounded number of
+// validations. Jobs are run with contexts derived from ctx, the workers exit
+// once ctx is done or the queue is stopped.
+type jobQueue struct {
+	ctx       context.Context
+	queue     chan *Job
+	retention time.Duration
+	run       func(ctx context.Context, job *Job)
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+	workers  sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func newJobQueue(ctx context.Context, workers, size int, retention time.Duration, run func(ctx context.Context, job *Job)) *jobQueue {
+	q := &jobQueue{
+		ctx:       ctx,
+		queue:     make(chan *Job, size),
+		retention: retention,
+		run:       run,
+		stopped:   make(chan struct{}),
+		jobs:      map[string]*Job{},
+	}
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+func (q *jobQueue) work() {
+	defer q.workers.Done()
+	for {
+		// prefer exiting over picking up another job
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.stopped:
+			return
+		default:
+		}
+
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.stopped:
+			return
+		case job := <-q.queue:
+			metrics.QueuedJobs(len(q.queue))
+			q.run(q.ctx, job)
+		}
+	}
+}
+
+// stop rejects further jobs, lets the workers finish their current job and
+// waits for them to exit. Queued jobs are not started anymore.
+func (q *jobQueue) stop() {
+	q.stopOnce.Do(func() {
+		q.mu.Lock()
+		close(q.stopped)
+		q.mu.Unlock()
+	})
+	q.workers.Wait()
+}
+
+// submit assigns an ID to job and queues it
+func (q *jobQueue) submit(job *Job) error {
+	id, err := newJobID()
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire(time.Now())
+
+	select {
+	case <-q.stopped:
+		return errStopped
+	case <-q.ctx.Done():
+		return errStopped
+	default:
+	}
+
+	job.ID = id
+	job.Status = jobQueued
+	job.Created = time.Now()
+
+	select {
+	case q.queue <- job:
+	default:
+		return errQueueFull
+	}
+	q.jobs[id] = job
+	metrics.QueuedJobs(len(q.queue))
+	return nil
+}
+
+// get returns a copy of the job with the given ID
+func (q *jobQueue) get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// update changes a job while no copies of it are made
+func (q *jobQueue) update(job *Job, change func(job *Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(job)
+}
+
+// expire removes jobs which finished more than the retention ago. It must be
+// called with mu held.
+func (q *jobQueue) expire(now time.Time) {
+	for id, job := range q.jobs {
+		if job.Finished != nil && now.Sub(*job.Finished) > q.retention {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func (s *server) submitJob(writer http.ResponseWriter, request *http.Request) {
+	if request.Body == nil {
+		http.Error(writer, "body can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	var jobReq jobRequest
+	err := httpbody.DecodeStrict(request.Body, &jobReq)
+	if err != nil {
+		httpbody.Error(writer, err, http.StatusBadRequest)
+		return
+	}
+
+	u, err := url.ParseRequestURI(jobReq.URL)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := checkCallback(jobReq.Callback); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schemas := s.schemas.Current()
+	versions, err := schemas.ParseVersions(jobReq.Versions)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job := &Job{
+		URL:      jobReq.URL,
+		Callback: jobReq.Callback,
+		target:   u,
+		opts:     ValidationOptions{Versions: versions, Schemas: schemas, Deep: jobReq.Deep},
+	}
+	s.queueJob(writer, job)
+}
+
+// checkCallback returns an error if callback is neither empty nor an http or
+// https URL
+func checkCallback(callback string) error {
+	if callback == "" {
+		return nil
+	}
+	u, err := url.ParseRequestURI(callback)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("callback must be an http or https URL")
+	}
+	return nil
+}
+
+// queueJob submits a job and responds with its status
+func (s *server) queueJob(writer http.ResponseWriter, job *Job) {
+	err := s.jobs.submit(job)
+	if err == errQueueFull || err == errStopped {
+		writer.Header().Set("Retry-After", queueFullRetry)
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status, _ := s.jobs.get(job.ID)
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Header().Add("Location", "/v2/jobs/"+job.ID)
+	writer.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(writer).Encode(status)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *server) getJob(writer http.ResponseWriter, request *http.Request) {
+	job, ok := s.jobs.get(pat.Param(request, "id"))
+	if !ok {
+		http.Error(writer, "job not found", http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	err := json.NewEncoder(writer).Encode(job)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// jobFailure is posted to the callback if a job failed
+type jobFailure struct {
+	Error string `json:"error"`
+}
+
+// runJob validates the URL or the directory of a job and posts the result to
+// its callback
+func (s *server) runJob(ctx context.Context, job *Job) {
+	timeout := jobTimeout
+	if job.directory != nil {
+		timeout = s.directoryTimeout(len(job.directory))
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	s.jobs.update(job, func(job *Job) {
+		now := time.Now()
+		job.Status = jobRunning
+		job.Started = &now
+	})
+
+	var valRes URLValidationResponse
+	var report DirectoryReport
+	var err error
+	if job.directory != nil {
+		report = s.checkDirectory(ctx, metrics.RouteV2Directory, job.directory, job.opts)
+	} else {
+		valRes, err = s.checkURL(ctx, job.target, job.opts)
+		if err == nil {
+			recordChecks(metrics.RouteV2Jobs, valRes)
+		}
+	}
+
+	s.jobs.update(job, func(job *Job) {
+		now := time.Now()
+		job.Finished = &now
+		switch {
+		case err != nil:
+			job.Status = jobFailed
+			job.Error = err.Error()
+			return
+		case job.directory != nil:
+			job.Directory = &report
+		default:
+			job.Result = &valRes
+		}
+		job.Status = jobDone
+	})
+
+	if job.Callback == "" {
+		return
+	}
+
+	status := jobDone
+	var payload interface{} = valRes
+	switch {
+	case err != nil:
+		status = jobFailed
+		payload = jobFailure{Error: err.Error()}
+	case job.directory != nil:
+		payload = report
+	}
+	if err := s.notify(ctx, job.Callback, job.ID, status, payload); err != nil {
+		s.jobs.update(job, func(job *Job) {
+			job.CallbackError = err.Error()
+		})
+	}
+}
+
+// directoryTimeout limits a directory job to the time its endpoints take if
+// each of them runs into the FetchTimeout, but at least to jobTimeout.
+// Endpoints which aren't validated by then are reported as errors.
+func (s *server) directoryTimeout(spaces int) time.Duration {
+	parallelism := s.cfg.BatchParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	rounds := (spaces + parallelism - 1) / parallelism
+	timeout := time.Duration(rounds) * s.cfg.FetchTimeout
+	if timeout < jobTimeout {
+		timeout = jobTimeout
+	}
+	return timeout
+}
+
+// notify posts the result of a job to its callback, along with its ID and
+// status in the X-Job-ID and X-Job-Status headers. The callback is requested
+// with the same restrictions as endpoints and redirects aren't followed.
+func (s *server) notify(ctx context.Context, callback, id, status string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", callback, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Job-ID", id)
+	req.Header.Set("X-Job-Status", status)
+
+	client := s.newClient(false)
+	defer client.CloseIdleConnections()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return unwrapURLError(err)
+	}
+	_ = response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("callback responded with status %s", response.Status)
+	}
+	return nil
+}
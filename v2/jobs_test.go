@@ -0,0 +1,267 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/spaceapi/validator/metrics"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEndpointServer serves validSpace with the logo served by itself and
+// sends the bodies posted to /callback on the returned channel
+func newEndpointServer() (*httptest.Server, chan []byte) {
+	callbacks := make(chan []byte, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(strings.Replace(validSpace, "https://example.com/logo.png", "http://"+r.Host+"/logo.png", 1)))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	})
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		callbacks <- body
+	})
+	return httptest.NewServer(mux), callbacks
+}
+
+func TestJob(t *testing.T) {
+	ts, callbacks := newEndpointServer()
+	defer ts.Close()
+	mux, stop := newTestMux()
+	defer stop()
+
+	rr := forgeRequest(t, mux.ServeHTTP, "POST", "/v2/jobs", strings.NewReader(`{"url": "`+ts.URL+`/status.json", "callback": "`+ts.URL+`/callback"}`))
+
+	if status := rr.Code; status != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v (%s)",
+			status, http.StatusAccepted, rr.Body.String())
+	}
+
+	var job Job
+	err := json.NewDecoder(rr.Body).Decode(&job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || rr.Header().Get("Location") != "/v2/jobs/"+job.ID {
+		t.Fatalf("handler returned wrong job: %+v %v", job, rr.Header().Get("Location"))
+	}
+
+	select {
+	case body := <-callbacks:
+		var resp URLValidationResponse
+		err := json.Unmarshal(body, &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Valid {
+			t.Errorf("callback received wrong result: %+v", resp)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("callback was not called")
+	}
+
+	deadline := time.Now().Add(time.Second * 10)
+	for job.Status != jobDone && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+		rr = forgeRequest(t, mux.ServeHTTP, "GET", "/v2/jobs/"+job.ID, nil)
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v",
+				status, http.StatusOK)
+		}
+		job = Job{}
+		err = json.NewDecoder(rr.Body).Decode(&job)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if job.Status != jobDone || job.Result == nil || !job.Result.Valid || job.Finished == nil {
+		t.Errorf("handler returned wrong job: %+v", job)
+	}
+
+	rr = forgeRequest(t, metrics.Handler().ServeHTTP, "GET", "/metrics", nil)
+	if !strings.Contains(rr.Body.String(), `validations_total{result="valid",route="`+metrics.RouteV2Jobs+`"}`) {
+		t.Errorf("job was not recorded under %s", metrics.RouteV2Jobs)
+	}
+}
+
+func TestJobFailedCallback(t *testing.T) {
+	ts, callbacks := newEndpointServer()
+	defer ts.Close()
+
+	s := newServer(testConfig(), nil)
+	s.jobs = newJobQueue(context.Background(), 0, 1, time.Hour, s.runJob)
+
+	target, _ := url.Parse(ts.URL + "/status.json")
+	job := &Job{
+		URL:      target.String(),
+		Callback: ts.URL + "/callback",
+		target:   target,
+		opts:     ValidationOptions{Versions: []string{"99"}},
+	}
+	if err := s.jobs.submit(job); err != nil {
+		t.Fatal(err)
+	}
+	s.runJob(context.Background(), job)
+
+	if job.Status != jobFailed || job.Error == "" {
+		t.Errorf("wrong job: %+v", job)
+	}
+
+	select {
+	case body := <-callbacks:
+		var failure jobFailure
+		err := json.Unmarshal(body, &failure)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failure.Error != job.Error {
+			t.Errorf("callback received wrong error: got %q want %q", failure.Error, job.Error)
+		}
+	default:
+		t.Fatal("callback was not called")
+	}
+}
+
+func TestJobQueueStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan context.Context, 1)
+	release := make(chan struct{})
+	q := newJobQueue(ctx, 1, 2, time.Hour, func(ctx context.Context, job *Job) {
+		started <- ctx
+		<-release
+	})
+
+	first, second := &Job{}, &Job{}
+	for _, job := range []*Job{first, second} {
+		if err := q.submit(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		q.stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stop returned while a job was running")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second * 5):
+		t.Fatal("workers didn't exit")
+	}
+
+	select {
+	case <-started:
+		t.Error("queued job was started after stop")
+	default:
+	}
+	if err := q.submit(&Job{}); err != errStopped {
+		t.Errorf("wrong error: got %v want %v", err, errStopped)
+	}
+}
+
+func TestJobQueueCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	started, aborted := make(chan struct{}), make(chan struct{})
+	q := newJobQueue(ctx, 1, 1, time.Hour, func(ctx context.Context, job *Job) {
+		close(started)
+		<-ctx.Done()
+		close(aborted)
+	})
+	if err := q.submit(&Job{}); err != nil {
+		t.Fatal(err)
+	}
+
+	<-started
+	cancel()
+	select {
+	case <-aborted:
+	case <-time.After(time.Second * 5):
+		t.Fatal("running job was not cancelled")
+	}
+	q.stop()
+}
+
+func TestJobNotFound(t *testing.T) {
+	mux, stop := newTestMux()
+	defer stop()
+
+	rr := forgeRequest(t, mux.ServeHTTP, "GET", "/v2/jobs/unknown", nil)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
+
+func TestJobInvalidCallback(t *testing.T) {
+	mux, stop := newTestMux()
+	defer stop()
+
+	rr := forgeRequest(t, mux.ServeHTTP, "POST", "/v2/jobs", strings.NewReader(`{"url": "https://example.com/status.json", "callback": "ftp://example.com/"}`))
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func TestJobQueueFull(t *testing.T) {
+	s := &server{cfg: testConfig()}
+	s.jobs = newJobQueue(context.Background(), 0, 1, time.Hour, s.runJob)
+
+	body := `{"url": "https://example.com/status.json"}`
+	codes := []int{http.StatusAccepted, http.StatusServiceUnavailable}
+	for _, want := range codes {
+		rr := forgeRequest(t, s.submitJob, "POST", "/v2/jobs", strings.NewReader(body))
+
+		if status := rr.Code; status != want {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, want)
+		}
+	}
+}
+
+func TestJobExpiry(t *testing.T) {
+	q := newJobQueue(context.Background(), 0, 2, time.Hour, nil)
+
+	done, queued := &Job{}, &Job{}
+	for _, job := range []*Job{done, queued} {
+		if err := q.submit(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	finished := time.Now().Add(-time.Hour * 2)
+	done.Finished = &finished
+
+	q.mu.Lock()
+	q.expire(time.Now())
+	q.mu.Unlock()
+
+	if _, ok := q.get(done.ID); ok {
+		t.Error("finished job was not removed")
+	}
+	if _, ok := q.get(queued.ID); !ok {
+		t.Error("queued job was removed")
+	}
+}
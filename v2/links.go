@@ -0,0 +1,178 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"github.com/spaceapi/validator/lint"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kinds of linked resources, they determine the expected content type
+const (
+	linkImage    = "image"
+	linkPage     = "page"
+	linkFeed     = "feed"
+	linkCalendar = "calendar"
+	linkStream   = "stream"
+)
+
+// maxLinks is the maximum number of linked resources fetched per endpoint,
+// so a single validation can't be used to send lots of requests
+const maxLinks = 20
+
+// linkWorkers is the number of linked resources fetched in parallel
+const linkWorkers = 4
+
+var feedTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+	"application/rdf+xml":  true,
+	"application/xml":      true,
+	"text/xml":             true,
+}
+
+// LinkReport is the result of fetching a resource the endpoint links to
+type LinkReport struct {
+	Pointer          string `json:"pointer"`
+	URL              string `json:"url"`
+	Kind             string `json:"kind"`
+	Reachable        bool   `json:"reachable"`
+	StatusCode       int    `json:"statusCode,omitempty"`
+	ContentType      string `json:"contentType,omitempty"`
+	ContentTypeValid bool   `json:"contentTypeValid"`
+	Error            string `json:"error,omitempty"`
+}
+
+// documentLinks returns the resources a document links to
+func documentLinks(doc lint.Document) []LinkReport {
+	var links []LinkReport
+	add := func(pointer, kind string) {
+		if link, ok := doc.String(pointer); ok && link != "" {
+			links = append(links, LinkReport{Pointer: pointer, URL: link, Kind: kind})
+		}
+	}
+
+	add("/logo", linkImage)
+	add("/url", linkPage)
+
+	cams, _ := doc.Lookup("/cam")
+	if list, ok := cams.([]interface{}); ok {
+		for i := range list {
+			add("/cam/"+strconv.Itoa(i), linkPage)
+		}
+	}
+
+	feeds, _ := doc.Lookup("/feeds")
+	if feedMap, ok := feeds.(map[string]interface{}); ok {
+		for _, name := range sortedKeys(feedMap) {
+			kind := linkFeed
+			if name == "calendar" {
+				kind = linkCalendar
+			}
+			add("/feeds/"+escapePointer(name)+"/url", kind)
+		}
+	}
+
+	stream, _ := doc.Lookup("/stream")
+	if streamMap, ok := stream.(map[string]interface{}); ok {
+		for _, name := range sortedKeys(streamMap) {
+			add("/stream/"+escapePointer(name), linkStream)
+		}
+	}
+
+	return links
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkLinks fetches the linked resources of a document in parallel
+func (s *server) checkLinks(ctx context.Context, doc lint.Document) []LinkReport {
+	links := documentLinks(doc)
+	if len(links) == 0 {
+		return nil
+	}
+
+	client := s.newClient(false)
+	defer client.CloseIdleConnections()
+
+	jobs := make(chan *LinkReport)
+	var wg sync.WaitGroup
+	for i := 0; i < linkWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				s.checkLinkedResource(ctx, client, link)
+			}
+		}()
+	}
+
+	for i := range links {
+		if i >= maxLinks {
+			links[i].Error = fmt.Sprintf("not checked, only the first %d links are", maxLinks)
+			continue
+		}
+		jobs <- &links[i]
+	}
+	close(jobs)
+	wg.Wait()
+
+	return links
+}
+
+// checkLinkedResource requests a linked resource and checks its status and
+// content type. The body isn't read, as streams never end.
+func (s *server) checkLinkedResource(ctx context.Context, client *http.Client, link *LinkReport) {
+	req, err := http.NewRequest("GET", link.URL, nil)
+	if err != nil {
+		link.Error = err.Error()
+		return
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Origin", s.cfg.Origin)
+
+	response, err := client.Do(req)
+	if err != nil {
+		link.Error = unwrapURLError(err).Error()
+		return
+	}
+	_ = response.Body.Close()
+
+	link.StatusCode = response.StatusCode
+	link.ContentType = response.Header.Get("Content-Type")
+	if response.StatusCode >= 400 {
+		link.Error = "status " + response.Status
+		return
+	}
+	link.Reachable = true
+
+	mediaType, _, _ := mime.ParseMediaType(link.ContentType)
+	link.ContentTypeValid = validLinkType(link.Kind, strings.ToLower(mediaType))
+	if !link.ContentTypeValid {
+		link.Error = fmt.Sprintf("unexpected content type %q for a %s", link.ContentType, link.Kind)
+	}
+}
+
+func validLinkType(kind, mediaType string) bool {
+	switch kind {
+	case linkImage:
+		return strings.HasPrefix(mediaType, "image/")
+	case linkFeed:
+		return feedTypes[mediaType]
+	case linkCalendar:
+		return mediaType == "text/calendar"
+	}
+	return true
+}
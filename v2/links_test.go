@@ -0,0 +1,208 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// linkedSpace is served with {{base}} replaced by the URL of the test server
+var linkedSpace = `{
+	"api_compatibility": ["14"],
+	"space": "my cool space",
+	"logo": "{{base}}/logo.png",
+	"url": "{{base}}/",
+	"location": {
+		"lon": 9.236,
+		"lat": 48.777
+	},
+	"contact": {
+		"email": "info@example.com"
+	},
+	"cam": ["{{base}}/cam.jpg", "{{base}}/missing.jpg"],
+	"feeds": {
+		"blog": {"type": "rss", "url": "{{base}}/blog.html"},
+		"calendar": {"type": "ical", "url": "{{base}}/events.ics"}
+	},
+	"stream": {
+		"mjpeg": "{{base}}/stream"
+	}
+}`
+
+func newLinkedServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Replace(linkedSpace, "{{base}}", "http://"+r.Host, -1)))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+	})
+	for path, contentType := range map[string]string{
+		"/logo.png":   "image/png",
+		"/cam.jpg":    "image/jpeg",
+		"/blog.html":  "text/html",
+		"/events.ics": "text/calendar; charset=utf-8",
+	} {
+		contentType := contentType
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+		})
+	}
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=frame")
+		w.(http.Flusher).Flush()
+		// a stream never ends, the check must not wait for its body
+		<-r.Context().Done()
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestValidateUrlDeep(t *testing.T) {
+	ts := newLinkedServer()
+	defer ts.Close()
+
+	rr := forgeValidateURLRequest(t, strings.NewReader(`{"url": "`+ts.URL+`/status.json", "deep": true}`))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := URLValidationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		kind             string
+		reachable        bool
+		contentTypeValid bool
+	}{
+		"/logo":               {linkImage, true, true},
+		"/url":                {linkPage, true, true},
+		"/cam/0":              {linkPage, true, true},
+		"/cam/1":              {linkPage, false, false},
+		"/feeds/blog/url":     {linkFeed, true, false},
+		"/feeds/calendar/url": {linkCalendar, true, true},
+		"/stream/mjpeg":       {linkStream, true, true},
+	}
+
+	if len(resp.Links) != len(want) {
+		t.Fatalf("handler returned wrong links: %+v", resp.Links)
+	}
+	for _, link := range resp.Links {
+		w, ok := want[link.Pointer]
+		if !ok {
+			t.Errorf("unexpected link %v", link.Pointer)
+			continue
+		}
+		if link.Kind != w.kind || link.Reachable != w.reachable || link.ContentTypeValid != w.contentTypeValid {
+			t.Errorf("wrong result for %v: got %+v", link.Pointer, link)
+		}
+	}
+}
+
+func TestValidateUrlNotDeep(t *testing.T) {
+	ts := newLinkedServer()
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL+"/status.json")
+
+	if resp.Links != nil {
+		t.Errorf("links checked without deep mode: %+v", resp.Links)
+	}
+}
+
+func TestCheckLinksLimit(t *testing.T) {
+	ts := newLinkedServer()
+	defer ts.Close()
+
+	var cams []interface{}
+	for i := 0; i < maxLinks+5; i++ {
+		cams = append(cams, ts.URL+"/cam.jpg")
+	}
+
+	links := testServer.checkLinks(context.Background(), map[string]interface{}{"cam": cams})
+
+	if len(links) != maxLinks+5 {
+		t.Fatalf("wrong number of links: %v", len(links))
+	}
+	if !links[maxLinks-1].Reachable || links[maxLinks].Reachable || links[maxLinks].Error == "" {
+		t.Errorf("links beyond the limit checked: %+v", links[maxLinks])
+	}
+}
+
+func TestValidateUrlDeepDeadline(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Replace(linkedSpace, "{{base}}", "http://"+r.Host, -1)))
+	})
+	// the logo never arrives, the check must give up before the WriteTimeout
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second * 5):
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	s := newServer(testConfig(), nil)
+	s.cfg.WriteTimeout = time.Millisecond * 400
+
+	start := time.Now()
+	rr := forgeRequest(t, s.validateURL, "POST", "/v2/validateURL", strings.NewReader(`{"url": "`+ts.URL+`/status.json", "deep": true}`))
+	if elapsed := time.Since(start); elapsed >= s.cfg.WriteTimeout {
+		t.Errorf("handler took %v, longer than the WriteTimeout", elapsed)
+	}
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := URLValidationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, link := range resp.Links {
+		if link.Pointer == "/logo" {
+			if link.Reachable || link.Error == "" {
+				t.Errorf("unfinished check reported as reachable: %+v", link)
+			}
+			return
+		}
+	}
+	t.Errorf("logo missing from links: %+v", resp.Links)
+}
+
+func TestValidateUrlDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second * 5):
+		}
+	}))
+	defer ts.Close()
+
+	s := newServer(testConfig(), nil)
+	s.cfg.WriteTimeout = time.Millisecond * 400
+
+	rr := forgeRequest(t, s.validateURL, "POST", "/v2/validateURL", strings.NewReader(`{"url": "`+ts.URL+`/status.json"}`))
+
+	if status := rr.Code; status != http.StatusGatewayTimeout {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusGatewayTimeout)
+	}
+}
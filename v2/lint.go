@@ -0,0 +1,42 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"github.com/spaceapi/validator/lint"
+)
+
+// lintDocument runs the default lint rules on a decoded document and
+// annotates the warnings with their position in document
+func lintDocument(ctx context.Context, raw map[string]interface{}, document []byte, env lint.Env) []lint.Warning {
+	warnings := lint.Run(ctx, lint.Document(raw), lint.DefaultRules, env)
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	offsets, err := valueOffsets(document)
+	if err != nil {
+		offsets = map[string]int{}
+	}
+
+	for i, warning := range warnings {
+		if offset, ok := offsets[warning.Pointer]; ok {
+			warnings[i].Line, warnings[i].Column = lineColumn(document, offset)
+		}
+	}
+	return warnings
+}
+
+// checkedLinks returns a lint.Env.CheckLink which looks up the results of
+// checkLinks instead of fetching the links again. The logo is checked first,
+// so it is never left out by maxLinks.
+func checkedLinks(links []LinkReport) func(ctx context.Context, url string) error {
+	return func(_ context.Context, url string) error {
+		for _, link := range links {
+			if link.URL == url && !link.Reachable {
+				return errors.New(link.Error)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spaceapi/validator/lint"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var swappedSpace = `{
+	"api_compatibility": ["14"],
+	"space": "my cool space",
+	"logo": "https://example.com/logo.png",
+	"url": "https://example.com",
+	"location": {
+		"lon": 9.236,
+		"lat": 148.777
+	},
+	"contact": {
+		"email": "info@example.com"
+	}
+}`
+
+func TestValidateJsonWarnings(t *testing.T) {
+	rr := forgeValidateJSONRequest(t, strings.NewReader(swappedSpace))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := JSONValidationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("handler returned wrong warnings: %+v", resp.Warnings)
+	}
+
+	want := lint.Warning{
+		Rule:     "location-swapped",
+		Severity: "warning",
+		Message:  resp.Warnings[0].Message,
+		Pointer:  "/location/lat",
+		Line:     8,
+		Column:   10,
+	}
+	if resp.Warnings[0] != want {
+		t.Errorf("handler returned wrong warning: got %+v want %+v", resp.Warnings[0], want)
+	}
+}
+
+func TestValidateUrlLogoWarning(t *testing.T) {
+	var logoFetches int32
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/logo.png" {
+				atomic.AddInt32(&logoFetches, 1)
+				http.NotFound(w, r)
+				return
+			}
+			space := strings.Replace(validSpace, "https://example.com/logo.png", "http://"+r.Host+"/logo.png", 1)
+			_, _ = w.Write([]byte(space))
+		}))
+	defer ts.Close()
+
+	// validSpace also names the unset email contact as issue channel
+	tests := map[bool]string{
+		false: "issue-channel-missing",
+		true:  "issue-channel-missing,logo-unreachable",
+	}
+
+	for deep, want := range tests {
+		atomic.StoreInt32(&logoFetches, 0)
+		body := fmt.Sprintf(`{"url": %q, "deep": %v}`, ts.URL, deep)
+		rr := forgeRequest(t, newServer(testConfig(), nil).validateURL, "POST", "/v2/validateURL", strings.NewReader(body))
+
+		var resp URLValidationResponse
+		err := json.NewDecoder(rr.Body).Decode(&resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rules []string
+		for _, warning := range resp.Warnings {
+			rules = append(rules, warning.Rule)
+		}
+		if strings.Join(rules, ",") != want {
+			t.Errorf("deep %v: handler returned wrong warnings: %+v", deep, resp.Warnings)
+		}
+		if resp.Valid != true {
+			t.Errorf("deep %v: handler returned wrong response: got %v want %v", deep, resp.Valid, true)
+		}
+
+		// the lint rule reuses the result of the linked resource check
+		wantFetches := int32(0)
+		if deep {
+			wantFetches = 1
+		}
+		if n := atomic.LoadInt32(&logoFetches); n != wantFetches {
+			t.Errorf("deep %v: logo was fetched %d times, want %d", deep, n, wantFetches)
+		}
+	}
+}
+
+func TestWriteGitHubWarnings(t *testing.T) {
+	var b strings.Builder
+	err := WriteReports(&b, FormatGitHub, []Report{{
+		Name: "spaceapi.json",
+		Result: JSONValidationResponse{
+			Valid: true,
+			Warnings: []lint.Warning{
+				{Rule: "location-swapped", Severity: "warning", Message: "swapped", Line: 8, Column: 10},
+				{Rule: "lastchange-stale", Severity: "info", Message: "stale"},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "::warning file=spaceapi.json,line=8,col=10,title=location-swapped::swapped\n" +
+		"::notice file=spaceapi.json,title=lastchange-stale::stale\n"
+	if b.String() != want {
+		t.Errorf("wrong annotations: got %q want %q", b.String(), want)
+	}
+}
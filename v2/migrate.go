@@ -0,0 +1,105 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/spaceapi/validator/internal/httpbody"
+	"github.com/spaceapi/validator/migrate"
+	"github.com/spaceapi/validator/schema"
+	"io/ioutil"
+	"net/http"
+)
+
+// MigrationResponse is the result of migrating a SpaceAPI document to a newer
+// version. The positions of the schema errors refer to Document indented by
+// two spaces, as written by MarshalDocument.
+type MigrationResponse struct {
+	Valid        bool             `json:"valid"`
+	From         string           `json:"from"`
+	To           string           `json:"to"`
+	Document     interface{}      `json:"document"`
+	Changes      []migrate.Change `json:"changes"`
+	Todos        []migrate.Todo   `json:"todos,omitempty"`
+	SchemaErrors []SchemaError    `json:"schemaErrors,omitempty"`
+}
+
+func (s *server) migrate(writer http.ResponseWriter, request *http.Request) {
+	if request.Body == nil {
+		http.Error(writer, "body can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		httpbody.Error(writer, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := Migrate(body, request.URL.Query().Get("version"), s.schemas.Current())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(resp)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// Migrate rewrites a SpaceAPI document for the target version, or the newest
+// stable version if target is empty, and validates the result against it
+func Migrate(body []byte, target string, schemas *schema.Set) (MigrationResponse, error) {
+	if target == "" {
+		target = schemas.Latest()
+	}
+	versions, err := schemas.ParseVersions([]string{target})
+	if err != nil {
+		return MigrationResponse{}, err
+	}
+
+	result, err := migrate.Migrate(body, versions[0])
+	if err != nil {
+		return MigrationResponse{}, err
+	}
+
+	document, err := MarshalDocument(result.Document)
+	if err != nil {
+		return MigrationResponse{}, err
+	}
+	res, err := schemas.Validate(document, []string{result.To})
+	if err != nil {
+		return MigrationResponse{}, err
+	}
+
+	resp := MigrationResponse{
+		Valid:    res.Valid,
+		From:     result.From,
+		To:       result.To,
+		Document: result.Document,
+		Changes:  result.Changes,
+		Todos:    result.Todos,
+	}
+	if resp.Changes == nil {
+		resp.Changes = []migrate.Change{}
+	}
+	_, resp.SchemaErrors, _, _ = schemaResult(res, document)
+
+	return resp, nil
+}
+
+// MarshalDocument encodes a document indented by two spaces. Unlike
+// json.MarshalIndent, characters like & in URLs aren't escaped.
+func MarshalDocument(document interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
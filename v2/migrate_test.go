@@ -0,0 +1,89 @@
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var space13 = `{
+	"api": "0.13",
+	"space": "my cool space",
+	"logo": "https://example.com/logo.png",
+	"url": "https://example.com",
+	"location": {
+		"lon": 9.236,
+		"lat": 48.777
+	},
+	"state": {
+		"open": false
+	},
+	"contact": {
+		"email": "info@example.com",
+		"jabber": "space@jabber.example.com"
+	},
+	"issue_report_channels": ["email"]
+}`
+
+func TestMigrateLatest(t *testing.T) {
+	rr := forgeRequest(t, testServer.migrate, "POST", "/v2/migrate", strings.NewReader(space13))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := MigrationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.Valid || resp.From != "13" || resp.To != "15" {
+		t.Errorf("handler returned wrong result: %+v", resp)
+	}
+	if len(resp.Changes) != 3 {
+		t.Errorf("handler returned wrong changes: %v", resp.Changes)
+	}
+}
+
+func TestMigrateInvalidResult(t *testing.T) {
+	rr := forgeRequest(t, testServer.migrate, "POST", "/v2/migrate?version=14", strings.NewReader(`{"api": "0.13", "space": "my cool space"}`))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := MigrationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Valid || len(resp.SchemaErrors) == 0 {
+		t.Errorf("handler returned wrong result: %+v", resp)
+	}
+	if resp.SchemaErrors[0].Line == 0 {
+		t.Errorf("schema error without position: %+v", resp.SchemaErrors[0])
+	}
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	rr := forgeRequest(t, testServer.migrate, "POST", "/v2/migrate?version=99", strings.NewReader(space13))
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func TestMigrateTooOld(t *testing.T) {
+	rr := forgeRequest(t, testServer.migrate, "POST", "/v2/migrate", strings.NewReader(`{"api": "0.12"}`))
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
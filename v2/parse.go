@@ -0,0 +1,122 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Likely causes of endpoint bodies which aren't a JSON object
+const (
+	causeHTML      = "html"
+	causeBOM       = "bom"
+	causeJSONP     = "jsonp"
+	causeTruncated = "truncated"
+	causeNotObject = "notObject"
+)
+
+var causeHints = map[string]string{
+	causeHTML:      "the endpoint serves an HTML page, e.g. a login, error or maintenance page",
+	causeBOM:       "the document starts with a UTF-8 byte order mark, which is not allowed in JSON",
+	causeJSONP:     "the document is wrapped in a JSONP callback, serve plain JSON instead",
+	causeTruncated: "the document ends unexpectedly, the response may have been cut off",
+	causeNotObject: "the document is valid JSON, but not an object",
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var jsonpPattern = regexp.MustCompile(`^[A-Za-z_$][\w$.]*\s*\(`)
+
+// ParseError describes why an endpoint body couldn't be parsed as a JSON
+// object
+type ParseError struct {
+	Error   string `json:"error"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Excerpt string `json:"excerpt"`
+	Cause   string `json:"cause,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// parseObject decodes body into a JSON object. If this fails, a ParseError
+// with the position of the error and the likely cause is returned.
+func parseObject(body []byte) (map[string]interface{}, *ParseError) {
+	var raw map[string]interface{}
+	err := json.Unmarshal(body, &raw)
+	if err == nil && raw != nil {
+		return raw, nil
+	}
+
+	parseErr := &ParseError{Cause: parseCause(body, err)}
+	parseErr.Hint = causeHints[parseErr.Cause]
+
+	offset := 0
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case len(bytes.TrimSpace(body)) == 0:
+		parseErr.Error = "document is empty"
+	case errors.As(err, &syntaxErr):
+		parseErr.Error = syntaxErr.Error()
+		// the offset is after the invalid character, or at the end of the
+		// input for truncated documents
+		offset = int(syntaxErr.Offset)
+		if offset < len(body) {
+			offset--
+		}
+	case errors.As(err, &typeErr):
+		parseErr.Error = "document is a JSON " + typeErr.Value + ", not an object"
+	case err != nil:
+		parseErr.Error = err.Error()
+	default:
+		parseErr.Error = "document is JSON null, not an object"
+	}
+	for offset > 0 && offset < len(body) && !utf8.RuneStart(body[offset]) {
+		offset--
+	}
+
+	parseErr.Line, parseErr.Column = lineColumn(body, offset)
+	parseErr.Excerpt = excerptAround(body, offset)
+
+	return nil, parseErr
+}
+
+// parseCause guesses why body isn't a JSON object
+func parseCause(body []byte, err error) string {
+	if bytes.HasPrefix(body, utf8BOM) {
+		return causeBOM
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return causeHTML
+	}
+	if jsonpPattern.Match(trimmed) {
+		return causeJSONP
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		if syntaxErr.Error() == "unexpected end of JSON input" {
+			return causeTruncated
+		}
+		return ""
+	}
+
+	return causeNotObject
+}
+
+// excerptAround quotes the body around offset, starting at most half an
+// excerpt before it
+func excerptAround(body []byte, offset int) string {
+	start := offset - excerptSize/2
+	if start <= 0 {
+		return excerpt(body, excerptSize)
+	}
+	for start < len(body) && !utf8.RuneStart(body[start]) {
+		start++
+	}
+	return "…" + excerpt(body[start:], excerptSize)
+}
@@ -0,0 +1,124 @@
+package v2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateUrlParseError(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<!DOCTYPE html>\n<html><body>Please log in</body></html>"))
+		}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.Valid != false || resp.Reachable != true {
+		t.Errorf("handler returned wrong result: valid %v reachable %v", resp.Valid, resp.Reachable)
+	}
+	if resp.ParseError == nil {
+		t.Fatalf("parse error missing")
+	}
+	if resp.ParseError.Cause != causeHTML {
+		t.Errorf("wrong cause: got %v want %v", resp.ParseError.Cause, causeHTML)
+	}
+	if resp.ParseError.Line != 1 || resp.ParseError.Column != 1 {
+		t.Errorf("wrong position: got %v:%v want 1:1", resp.ParseError.Line, resp.ParseError.Column)
+	}
+	if !strings.HasPrefix(resp.ParseError.Excerpt, "<!DOCTYPE html>") {
+		t.Errorf("wrong excerpt: %q", resp.ParseError.Excerpt)
+	}
+}
+
+func TestValidateUrlEmptyBody(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+		}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.Valid != false || resp.Reachable != true {
+		t.Errorf("handler returned wrong result: valid %v reachable %v", resp.Valid, resp.Reachable)
+	}
+	if resp.ParseError == nil {
+		t.Fatalf("parse error missing")
+	}
+	if resp.ParseError.Cause != causeTruncated || resp.ParseError.Error != "document is empty" {
+		t.Errorf("wrong parse error: got %+v", resp.ParseError)
+	}
+	if resp.Message == "" {
+		t.Errorf("message should not be empty")
+	}
+}
+
+func TestValidateUrlParseErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("not json at all"))
+		}))
+	defer ts.Close()
+
+	rr := forgeValidateURLRequest(t, strings.NewReader(`{"url": "`+ts.URL+`"}`))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+}
+
+func TestParseObject(t *testing.T) {
+	tests := []struct {
+		body   string
+		cause  string
+		line   int
+		column int
+	}{
+		{"\xEF\xBB\xBF{\"api\": \"0.13\"}", causeBOM, 1, 1},
+		{"callback({\"api\": \"0.13\"});", causeJSONP, 1, 1},
+		{"{\n  \"api\": \"0.13\",\n  \"space\": \"Bo", causeTruncated, 3, 15},
+		{"[1, 2, 3]", causeNotObject, 1, 1},
+		{"null", causeNotObject, 1, 1},
+		{"{\n  \"api\": 0.13,,\n}", "", 2, 15},
+		{"  <html>", causeHTML, 1, 3},
+		{"", causeTruncated, 1, 1},
+		{" \n", causeTruncated, 1, 1},
+	}
+
+	for _, test := range tests {
+		raw, parseErr := parseObject([]byte(test.body))
+		if raw != nil || parseErr == nil {
+			t.Errorf("%q parsed as an object", test.body)
+			continue
+		}
+		if parseErr.Cause != test.cause {
+			t.Errorf("wrong cause for %q: got %q want %q", test.body, parseErr.Cause, test.cause)
+		}
+		if parseErr.Cause != "" && parseErr.Hint == "" {
+			t.Errorf("missing hint for %q", test.body)
+		}
+		if parseErr.Line != test.line || parseErr.Column != test.column {
+			t.Errorf("wrong position for %q: got %v:%v want %v:%v",
+				test.body, parseErr.Line, parseErr.Column, test.line, test.column)
+		}
+	}
+
+	raw, parseErr := parseObject([]byte(`{"api": "0.13"}`))
+	if raw == nil || parseErr != nil {
+		t.Errorf("object not parsed: %+v", parseErr)
+	}
+}
+
+func TestExcerptAround(t *testing.T) {
+	body := []byte(strings.Repeat("a", 1000) + "!" + strings.Repeat("b", 1000))
+
+	got := excerptAround(body, 1000)
+	if !strings.HasPrefix(got, "…a") || !strings.HasSuffix(got, "b…") || !strings.Contains(got, "!") {
+		t.Errorf("wrong excerpt: %q", got)
+	}
+}
@@ -0,0 +1,210 @@
+package v2
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var errSyntax = errors.New("invalid JSON")
+
+// valueOffsets returns the byte offset of every value in a JSON document,
+// keyed by its JSON pointer
+func valueOffsets(data []byte) (map[string]int, error) {
+	l := locator{data: data, offsets: map[string]int{}}
+	err := l.value("")
+	if err != nil {
+		return nil, err
+	}
+	return l.offsets, nil
+}
+
+type locator struct {
+	data    []byte
+	pos     int
+	offsets map[string]int
+}
+
+func (l *locator) skipSpace() {
+	for l.pos < len(l.data) {
+		switch l.data[l.pos] {
+		case ' ', '\t', '\r', '\n':
+			l.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (l *locator) value(pointer string) error {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return errSyntax
+	}
+	l.offsets[pointer] = l.pos
+
+	switch l.data[l.pos] {
+	case '{':
+		return l.object(pointer)
+	case '[':
+		return l.array(pointer)
+	case '"':
+		_, err := l.str()
+		return err
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !strings.ContainsRune(" \t\r\n,]}", rune(l.data[l.pos])) {
+		l.pos++
+	}
+	if start == l.pos {
+		return errSyntax
+	}
+	return nil
+}
+
+func (l *locator) object(pointer string) error {
+	l.pos++
+	l.skipSpace()
+	if l.pos < len(l.data) && l.data[l.pos] == '}' {
+		l.pos++
+		return nil
+	}
+
+	for {
+		l.skipSpace()
+		key, err := l.str()
+		if err != nil {
+			return err
+		}
+
+		l.skipSpace()
+		if l.pos >= len(l.data) || l.data[l.pos] != ':' {
+			return errSyntax
+		}
+		l.pos++
+
+		err = l.value(pointer + "/" + escapePointer(key))
+		if err != nil {
+			return err
+		}
+
+		done, err := l.next('}')
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+func (l *locator) array(pointer string) error {
+	l.pos++
+	l.skipSpace()
+	if l.pos < len(l.data) && l.data[l.pos] == ']' {
+		l.pos++
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		err := l.value(pointer + "/" + strconv.Itoa(i))
+		if err != nil {
+			return err
+		}
+
+		done, err := l.next(']')
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+// next consumes either a comma or the closing character of a container
+func (l *locator) next(closing byte) (bool, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return false, errSyntax
+	}
+
+	switch l.data[l.pos] {
+	case ',':
+		l.pos++
+		return false, nil
+	case closing:
+		l.pos++
+		return true, nil
+	}
+	return false, errSyntax
+}
+
+func (l *locator) str() (string, error) {
+	if l.pos >= len(l.data) || l.data[l.pos] != '"' {
+		return "", errSyntax
+	}
+
+	start := l.pos
+	for l.pos++; l.pos < len(l.data); l.pos++ {
+		switch l.data[l.pos] {
+		case '\\':
+			l.pos++
+		case '"':
+			l.pos++
+			var s string
+			err := json.Unmarshal(l.data[start:l.pos], &s)
+			return s, err
+		}
+	}
+	return "", errSyntax
+}
+
+func escapePointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// lineColumn converts a byte offset into a 1-based line and column, counting
+// the column in characters
+func lineColumn(data []byte, offset int) (int, int) {
+	line, column := 1, 1
+	for i := 0; i < offset && i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+		i += size
+	}
+	return line, column
+}
+
+// contextPointer converts a validator context like (root).location.lat into
+// a JSON pointer. As keys may contain dots themselves, the segments are
+// matched against the pointers present in the document.
+func contextPointer(context string, offsets map[string]int) (string, bool) {
+	if context != "(root)" && !strings.HasPrefix(context, "(root).") {
+		return "", false
+	}
+
+	segments := strings.Split(strings.TrimPrefix(context, "(root)"), ".")[1:]
+	return matchSegments("", segments, offsets)
+}
+
+func matchSegments(pointer string, segments []string, offsets map[string]int) (string, bool) {
+	if len(segments) == 0 {
+		_, ok := offsets[pointer]
+		return pointer, ok
+	}
+
+	for i := 1; i <= len(segments); i++ {
+		key := strings.Join(segments[:i], ".")
+		candidate := pointer + "/" + escapePointer(key)
+		if _, ok := offsets[candidate]; !ok {
+			continue
+		}
+		if result, ok := matchSegments(candidate, segments[i:], offsets); ok {
+			return result, true
+		}
+	}
+	return "", false
+}
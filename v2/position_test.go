@@ -0,0 +1,87 @@
+package v2
+
+import (
+	"testing"
+)
+
+var positionDocument = `{
+	"space": "my cool space",
+	"location": {
+		"lat": "48.777",
+		"lon": 9.236
+	},
+	"a.b": { "c/d": [1, {"e": "ü"}, true] },
+	"ümlaut": "x", "end": null
+}`
+
+func TestValueOffsets(t *testing.T) {
+	offsets, err := valueOffsets([]byte(positionDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		pointer string
+		line    int
+		column  int
+	}{
+		{"", 1, 1},
+		{"/space", 2, 11},
+		{"/location", 3, 14},
+		{"/location/lat", 4, 10},
+		{"/a.b/c~1d/1/e", 7, 28},
+		{"/a.b/c~1d/2", 7, 34},
+		{"/end", 8, 24},
+	}
+
+	for _, c := range cases {
+		offset, ok := offsets[c.pointer]
+		if !ok {
+			t.Errorf("pointer %q not found", c.pointer)
+			continue
+		}
+
+		line, column := lineColumn([]byte(positionDocument), offset)
+		if line != c.line || column != c.column {
+			t.Errorf("wrong position for %q: got %v:%v want %v:%v", c.pointer, line, column, c.line, c.column)
+		}
+	}
+}
+
+func TestValueOffsetsInvalid(t *testing.T) {
+	for _, document := range []string{"", "{", `{"a" 1}`, `[1 2]`, `{"a": }`} {
+		_, err := valueOffsets([]byte(document))
+		if err == nil {
+			t.Errorf("expected an error for %q", document)
+		}
+	}
+}
+
+func TestContextPointer(t *testing.T) {
+	offsets, err := valueOffsets([]byte(positionDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"(root)":                 "",
+		"(root).location.lat":    "/location/lat",
+		"(root).a.b.c/d.1.e":     "/a.b/c~1d/1/e",
+		"(root).ümlaut":          "/ümlaut",
+		"(root).location.height": "",
+	}
+
+	for context, want := range cases {
+		pointer, ok := contextPointer(context, offsets)
+		if want == "" && context != "(root)" {
+			if ok {
+				t.Errorf("expected no pointer for %q, got %q", context, pointer)
+			}
+			continue
+		}
+
+		if !ok || pointer != want {
+			t.Errorf("wrong pointer for %q: got %q want %q", context, pointer, want)
+		}
+	}
+}
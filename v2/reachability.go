@@ -0,0 +1,149 @@
+package v2
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Classes of unreachable endpoints
+const (
+	unreachableDNSNotFound       = "dnsNotFound"
+	unreachableDNS               = "dns"
+	unreachableConnectionRefused = "connectionRefused"
+	unreachableTimeout           = "timeout"
+	unreachableTLSHandshake      = "tlsHandshake"
+	unreachableHTTPStatus        = "httpStatus"
+	unreachableBlocked           = "blocked"
+	unreachableRedirect          = "redirect"
+	unreachableOther             = "other"
+)
+
+// excerptSize is the maximum number of bytes of a body quoted in reports
+const excerptSize = 512
+
+// Reachability explains why an endpoint couldn't be fetched
+type Reachability struct {
+	Class       string `json:"class"`
+	Error       string `json:"error"`
+	StatusCode  int    `json:"statusCode,omitempty"`
+	BodyExcerpt string `json:"bodyExcerpt,omitempty"`
+}
+
+// fetchFailure classifies an error returned by http.Client.Do
+func fetchFailure(err error) *Reachability {
+	err = unwrapURLError(err)
+	return &Reachability{Class: classifyFetchError(err), Error: err.Error()}
+}
+
+func classifyFetchError(err error) string {
+	var blocked *blockedError
+	if errors.As(err, &blocked) {
+		return unreachableBlocked
+	}
+
+	if errors.Is(err, errRedirectLoop) || errors.Is(err, errTooManyRedirects) {
+		return unreachableRedirect
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsNotFound {
+			return unreachableDNSNotFound
+		}
+		return unreachableDNS
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return unreachableConnectionRefused
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return unreachableTimeout
+	}
+
+	if isTLSError(err) {
+		return unreachableTLSHandshake
+	}
+
+	return unreachableOther
+}
+
+// isTLSError reports whether err occurred during the TLS handshake. Most
+// handshake errors of crypto/tls aren't typed, so they are recognized by
+// their prefix.
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var certErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	if errors.As(err, &recordErr) || errors.As(err, &certErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &authorityErr) {
+		return true
+	}
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		msg := err.Error()
+		if strings.HasPrefix(msg, "tls: ") || strings.HasPrefix(msg, "remote error: tls: ") {
+			return true
+		}
+		// net/http replaces the record header error of plain HTTP servers
+		if msg == "http: server gave HTTP response to HTTPS client" {
+			return true
+		}
+	}
+	return false
+}
+
+// statusFailure describes an error response of the endpoint
+func statusFailure(response *http.Response, body []byte) *Reachability {
+	return &Reachability{
+		Class:       unreachableHTTPStatus,
+		Error:       fmt.Sprintf("endpoint responded with %s", response.Status),
+		StatusCode:  response.StatusCode,
+		BodyExcerpt: excerpt(body, excerptSize),
+	}
+}
+
+// excerpt returns at most the first size bytes of body for quoting. Invalid
+// UTF-8 and control characters other than newlines and tabs are replaced and
+// carriage returns dropped, so the excerpt is safe to display.
+func excerpt(body []byte, size int) string {
+	truncated := len(body) > size
+	if truncated {
+		body = body[:size]
+	}
+
+	var b strings.Builder
+	for len(body) > 0 {
+		r, n := utf8.DecodeRune(body)
+		if r == utf8.RuneError && truncated && !utf8.FullRune(body) {
+			// a character cut off at the end of the excerpt
+			break
+		}
+		body = body[n:]
+
+		switch {
+		case r == '\r':
+		case r == '\n' || r == '\t':
+			b.WriteRune(r)
+		case r == utf8.RuneError || unicode.IsControl(r):
+			b.WriteRune(utf8.RuneError)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	if truncated {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String())
+}
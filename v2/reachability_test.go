@@ -0,0 +1,152 @@
+package v2
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestValidateUrlReachabilityStatus(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("<h1>Maintenance</h1>\r\n" + strings.Repeat("x", 1000)))
+		}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.Reachability == nil {
+		t.Fatalf("reachability missing")
+	}
+	if resp.Reachability.Class != unreachableHTTPStatus {
+		t.Errorf("wrong class: got %v want %v", resp.Reachability.Class, unreachableHTTPStatus)
+	}
+	if resp.Reachability.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("wrong status code: got %v want %v", resp.Reachability.StatusCode, http.StatusServiceUnavailable)
+	}
+	if !strings.HasPrefix(resp.Reachability.BodyExcerpt, "<h1>Maintenance</h1>\nxxx") ||
+		!strings.HasSuffix(resp.Reachability.BodyExcerpt, "…") {
+		t.Errorf("wrong body excerpt: %q", resp.Reachability.BodyExcerpt)
+	}
+}
+
+func TestValidateUrlReachabilityRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	resp := postValidateURL(t, testServer, "http://"+addr)
+
+	if resp.Reachability == nil || resp.Reachability.Class != unreachableConnectionRefused {
+		t.Errorf("wrong reachability: %+v", resp.Reachability)
+	}
+}
+
+func TestValidateUrlReachabilityTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+		}))
+	defer ts.Close()
+	defer close(done)
+
+	s := &server{cfg: testConfig()}
+	s.cfg.FetchTimeout = 50 * time.Millisecond
+
+	resp := postValidateURL(t, s, ts.URL)
+
+	if resp.Reachability == nil || resp.Reachability.Class != unreachableTimeout {
+		t.Errorf("wrong reachability: %+v", resp.Reachability)
+	}
+}
+
+func TestValidateUrlReachabilityBlocked(t *testing.T) {
+	resp := postValidateURL(t, &server{cfg: testConfig()}, "http://10.0.0.1/")
+
+	if resp.Reachability == nil || resp.Reachability.Class != unreachableBlocked {
+		t.Errorf("wrong reachability: %+v", resp.Reachability)
+	}
+}
+
+func TestValidateUrlReachabilityTLSHandshake(t *testing.T) {
+	// a plain HTTP server can't complete a TLS handshake
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, strings.Replace(ts.URL, "http://", "https://", 1))
+
+	if resp.Reachability == nil || resp.Reachability.Class != unreachableTLSHandshake {
+		t.Errorf("wrong reachability: %+v", resp.Reachability)
+	}
+}
+
+func TestValidateUrlReachableWithoutReachability(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(validSpace))
+		}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.Reachability != nil {
+		t.Errorf("unexpected reachability: %+v", resp.Reachability)
+	}
+}
+
+func TestClassifyFetchError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, unreachableDNSNotFound},
+		{&net.DNSError{Err: "server misbehaving", Name: "example.org"}, unreachableDNS},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, unreachableConnectionRefused},
+		{&url.Error{Op: "Get", Err: errors.New("remote error: tls: handshake failure")}, unreachableTLSHandshake},
+		{&url.Error{Op: "Get", Err: errTooManyRedirects}, unreachableRedirect},
+		{errors.New("unexpected EOF"), unreachableOther},
+	}
+
+	for _, test := range tests {
+		if got := classifyFetchError(test.err); got != test.want {
+			t.Errorf("wrong class for %v: got %v want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		body string
+		size int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"a\x00b\x1bc", 10, "a�b�c"},
+		{"line\r\nnext", 20, "line\nnext"},
+		{"abcdef", 3, "abc…"},
+		{"aé", 2, "a…"},
+		{"a\xffb", 10, "a�b"},
+	}
+
+	for _, test := range tests {
+		if got := excerpt([]byte(test.body), test.size); got != test.want {
+			t.Errorf("wrong excerpt of %q: got %q want %q", test.body, got, test.want)
+		}
+	}
+}
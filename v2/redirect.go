@@ -0,0 +1,97 @@
+package v2
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var errRedirectLoop = errors.New("redirect loop")
+var errTooManyRedirects = errors.New("too many redirects")
+
+// permanentRedirects maps temporary redirect status codes to their
+// permanent counterpart
+var permanentRedirects = map[int]int{
+	http.StatusFound:             http.StatusMovedPermanently,
+	http.StatusSeeOther:          http.StatusMovedPermanently,
+	http.StatusTemporaryRedirect: http.StatusPermanentRedirect,
+}
+
+// RedirectReport lists the redirects followed when fetching an endpoint
+type RedirectReport struct {
+	Hops      []RedirectHop `json:"hops"`
+	Downgrade bool          `json:"downgrade"`
+	Loop      bool          `json:"loop"`
+	TooMany   bool          `json:"tooMany"`
+	Warnings  []string      `json:"warnings,omitempty"`
+}
+
+// RedirectHop is a single response on the way to the endpoint
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location,omitempty"`
+}
+
+// redirectTracker records the redirects of a request. Its check method is
+// meant to be used as http.Client.CheckRedirect.
+type redirectTracker struct {
+	max    int
+	report RedirectReport
+}
+
+func (t *redirectTracker) check(req *http.Request, via []*http.Request) error {
+	previous := via[len(via)-1]
+	hop := RedirectHop{URL: previous.URL.String()}
+	if req.Response != nil {
+		hop.StatusCode = req.Response.StatusCode
+		hop.Location = req.Response.Header.Get("Location")
+	}
+	t.report.Hops = append(t.report.Hops, hop)
+
+	if permanent, ok := permanentRedirects[hop.StatusCode]; ok {
+		t.report.Warnings = append(t.report.Warnings, fmt.Sprintf(
+			"%s redirects with %d, use %d if the endpoint moved permanently",
+			hop.URL, hop.StatusCode, permanent,
+		))
+	}
+
+	if previous.URL.Scheme == "https" && req.URL.Scheme == "http" {
+		t.report.Downgrade = true
+		t.report.Warnings = append(t.report.Warnings, fmt.Sprintf(
+			"%s redirects from https to http", hop.URL,
+		))
+	}
+
+	for _, visited := range via {
+		if visited.URL.String() == req.URL.String() {
+			t.report.Loop = true
+			t.report.Hops = append(t.report.Hops, RedirectHop{URL: req.URL.String()})
+			return errRedirectLoop
+		}
+	}
+
+	if len(via) > t.max {
+		t.report.TooMany = true
+		return errTooManyRedirects
+	}
+
+	return nil
+}
+
+// result returns the report including the final response, or nil if no
+// redirect was followed
+func (t *redirectTracker) result(final *http.Response) *RedirectReport {
+	if len(t.report.Hops) == 0 {
+		return nil
+	}
+
+	if final != nil {
+		t.report.Hops = append(t.report.Hops, RedirectHop{
+			URL:        final.Request.URL.String(),
+			StatusCode: final.StatusCode,
+		})
+	}
+
+	return &t.report
+}
@@ -0,0 +1,132 @@
+package v2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateUrlRedirectChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/status.json", http.StatusFound)
+	})
+	mux.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(validSpace))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL+"/old")
+
+	if resp.Valid != true {
+		t.Errorf("handler returned wrong response: got %v want %v", resp.Valid, true)
+	}
+
+	if resp.Redirects == nil {
+		t.Fatalf("redirect report missing")
+	}
+
+	want := []RedirectHop{
+		{URL: ts.URL + "/old", StatusCode: 301, Location: "/moved"},
+		{URL: ts.URL + "/moved", StatusCode: 302, Location: "/status.json"},
+		{URL: ts.URL + "/status.json", StatusCode: 200},
+	}
+	if len(resp.Redirects.Hops) != len(want) {
+		t.Fatalf("wrong hops: got %+v want %+v", resp.Redirects.Hops, want)
+	}
+	for i, hop := range want {
+		if resp.Redirects.Hops[i] != hop {
+			t.Errorf("wrong hop %d: got %+v want %+v", i, resp.Redirects.Hops[i], hop)
+		}
+	}
+
+	if len(resp.Redirects.Warnings) != 1 || !strings.Contains(resp.Redirects.Warnings[0], "302") {
+		t.Errorf("wrong warnings: %v", resp.Redirects.Warnings)
+	}
+}
+
+func TestValidateUrlNoRedirects(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(validSpace))
+		}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.Redirects != nil {
+		t.Errorf("unexpected redirect report: %+v", resp.Redirects)
+	}
+}
+
+func TestValidateUrlRedirectLoop(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusMovedPermanently)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL+"/a")
+
+	if resp.Reachable != false {
+		t.Errorf("handler returned wrong reachability: got %v want %v", resp.Reachable, false)
+	}
+
+	if resp.Redirects == nil || resp.Redirects.Loop != true {
+		t.Fatalf("loop not detected: %+v", resp.Redirects)
+	}
+
+	if hops := len(resp.Redirects.Hops); hops != 3 {
+		t.Errorf("wrong number of hops: got %v want %v", hops, 3)
+	}
+}
+
+func TestValidateUrlTooManyRedirects(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, r.URL.Path+"x", http.StatusMovedPermanently)
+		}))
+	defer ts.Close()
+
+	s := &server{cfg: testConfig()}
+	s.cfg.MaxRedirects = 3
+
+	resp := postValidateURL(t, s, ts.URL+"/")
+
+	if resp.Redirects == nil || resp.Redirects.TooMany != true {
+		t.Fatalf("too many redirects not detected: %+v", resp.Redirects)
+	}
+
+	if hops := len(resp.Redirects.Hops); hops != 4 {
+		t.Errorf("wrong number of hops: got %v want %v", hops, 4)
+	}
+}
+
+func TestValidateUrlRedirectDowngrade(t *testing.T) {
+	plain := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(validSpace))
+		}))
+	defer plain.Close()
+
+	secure := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, plain.URL, http.StatusMovedPermanently)
+		}))
+	defer secure.Close()
+
+	resp := postValidateURL(t, testServer, secure.URL)
+
+	if resp.Redirects == nil || resp.Redirects.Downgrade != true {
+		t.Errorf("downgrade not detected: %+v", resp.Redirects)
+	}
+}
@@ -0,0 +1,50 @@
+package v2
+
+import (
+	"encoding/json"
+	"goji.io/pat"
+	"net/http"
+)
+
+// SchemaInfo describes a SpaceAPI version known to the validator
+type SchemaInfo struct {
+	Version string `json:"version"`
+	Status  string `json:"status"`
+	URL     string `json:"url"`
+}
+
+// listSchemas lists the versions of the schemas documents are validated
+// against
+func (s *server) listSchemas(writer http.ResponseWriter, _ *http.Request) {
+	schemas := s.schemas.Current()
+
+	infos := []SchemaInfo{}
+	for _, version := range schemas.Versions() {
+		schema, _ := schemas.Schema(version)
+		infos = append(infos, SchemaInfo{
+			Version: version,
+			Status:  schema.Status(),
+			URL:     "/v2/schemas/" + version,
+		})
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	err := json.NewEncoder(writer).Encode(infos)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// getSchema serves the raw JSON schema of a version
+func (s *server) getSchema(writer http.ResponseWriter, request *http.Request) {
+	schema, ok := s.schemas.Current().Schema(pat.Param(request, "version"))
+	if !ok {
+		http.Error(writer, "unknown SpaceAPI version", http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/schema+json")
+	writer.Header().Add("X-Schema-Status", schema.Status())
+	_, _ = writer.Write(schema.Raw)
+}
@@ -0,0 +1,82 @@
+package v2
+
+import (
+	"encoding/json"
+	"github.com/spaceapi/validator/schema"
+	"net/http"
+	"testing"
+)
+
+func TestListSchemas(t *testing.T) {
+	mux, stop := newTestMux()
+	defer stop()
+
+	rr := forgeRequest(t, mux.ServeHTTP, "GET", "/v2/schemas", nil)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var infos []SchemaInfo
+	err := json.NewDecoder(rr.Body).Decode(&infos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := map[string]string{}
+	for _, info := range infos {
+		status[info.Version] = info.Status
+	}
+	want := map[string]string{
+		"12": schema.StatusDeprecated,
+		"13": schema.StatusDeprecated,
+		"14": schema.StatusStable,
+		"15": schema.StatusStable,
+		"16": schema.StatusDraft,
+	}
+	for version, s := range want {
+		if status[version] != s {
+			t.Errorf("wrong status of %v: got %v want %v", version, status[version], s)
+		}
+	}
+	if infos[0].URL != "/v2/schemas/12" {
+		t.Errorf("wrong url: got %v want %v", infos[0].URL, "/v2/schemas/12")
+	}
+}
+
+func TestGetSchema(t *testing.T) {
+	mux, stop := newTestMux()
+	defer stop()
+
+	rr := forgeRequest(t, mux.ServeHTTP, "GET", "/v2/schemas/15", nil)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var doc map[string]interface{}
+	err := json.NewDecoder(rr.Body).Decode(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["$id"] != "https://schema.spaceapi.io/15.json" {
+		t.Errorf("wrong schema: %v", doc["$id"])
+	}
+	if rr.Header().Get("Content-Type") != "application/schema+json" {
+		t.Errorf("wrong content type: %v", rr.Header().Get("Content-Type"))
+	}
+}
+
+func TestGetSchemaUnknown(t *testing.T) {
+	mux, stop := newTestMux()
+	defer stop()
+
+	rr := forgeRequest(t, mux.ServeHTTP, "GET", "/v2/schemas/99", nil)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
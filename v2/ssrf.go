@@ -0,0 +1,87 @@
+package v2
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+type blockedNetwork struct {
+	network *net.IPNet
+	reason  string
+}
+
+// blockedNetworks are the destinations endpoints must not be fetched from,
+// so the validator can't be used to probe internal services
+var blockedNetworks = []blockedNetwork{
+	{mustParseCIDR("0.0.0.0/8"), "unspecified address"},
+	{mustParseCIDR("::/128"), "unspecified address"},
+	{mustParseCIDR("127.0.0.0/8"), "loopback address"},
+	{mustParseCIDR("::1/128"), "loopback address"},
+	{mustParseCIDR("10.0.0.0/8"), "private address"},
+	{mustParseCIDR("172.16.0.0/12"), "private address"},
+	{mustParseCIDR("192.168.0.0/16"), "private address"},
+	{mustParseCIDR("fc00::/7"), "private address"},
+	{mustParseCIDR("100.64.0.0/10"), "shared address"},
+	{mustParseCIDR("64:ff9b::/96"), "NAT64 address"},
+	{mustParseCIDR("169.254.0.0/16"), "link-local address"},
+	{mustParseCIDR("fe80::/10"), "link-local address"},
+	{mustParseCIDR("224.0.0.0/4"), "multicast address"},
+	{mustParseCIDR("ff00::/8"), "multicast address"},
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// blockedError is returned when dialing a blocked destination
+type blockedError struct {
+	ip     net.IP
+	reason string
+}
+
+func (e *blockedError) Error() string {
+	return fmt.Sprintf("destination %s is not allowed: %s", e.ip, e.reason)
+}
+
+// checkDestination returns a blockedError if ip is in one of the blocked
+// networks and not explicitly allowed
+func checkDestination(ip net.IP, allow []*net.IPNet) error {
+	for _, network := range allow {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	for _, blocked := range blockedNetworks {
+		if blocked.network.Contains(ip) {
+			return &blockedError{ip: ip, reason: blocked.reason}
+		}
+	}
+
+	return nil
+}
+
+// guardControl returns a net.Dialer Control function which refuses to
+// connect to blocked destinations. As it runs after DNS resolution for every
+// connection, it also covers redirects and hostnames resolving to internal
+// addresses.
+func guardControl(allow []*net.IPNet) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("dialing unresolved address %s", address)
+		}
+
+		return checkDestination(ip, allow)
+	}
+}
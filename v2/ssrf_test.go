@@ -0,0 +1,92 @@
+package v2
+
+import (
+	"github.com/spaceapi/validator/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckDestination(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":        true,
+		"::1":              true,
+		"10.1.2.3":         true,
+		"172.20.0.1":       true,
+		"192.168.178.1":    true,
+		"169.254.169.254":  true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"224.0.0.251":      true,
+		"0.0.0.0":          true,
+		"::ffff:127.0.0.1": true,
+		"100.64.0.1":       true,
+		"100.127.255.254":  true,
+		"64:ff9b::a00:1":   true,
+		"100.128.0.1":      false,
+		"93.184.216.34":    false,
+		"2606:4700::1111":  false,
+	}
+
+	for address, blocked := range cases {
+		err := checkDestination(net.ParseIP(address), nil)
+		if (err != nil) != blocked {
+			t.Errorf("wrong result for %s: got %v want blocked=%v", address, err, blocked)
+		}
+	}
+}
+
+func TestCheckDestinationAllowed(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.0.0.0/8")
+
+	err := checkDestination(net.ParseIP("10.1.2.3"), []*net.IPNet{network})
+	if err != nil {
+		t.Errorf("allowed destination was blocked: %v", err)
+	}
+
+	err = checkDestination(net.ParseIP("192.168.0.1"), []*net.IPNet{network})
+	if err == nil {
+		t.Errorf("destination outside the allowlist was not blocked")
+	}
+}
+
+func TestValidateUrlBlocked(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("blocked destination was requested")
+		}))
+	defer ts.Close()
+
+	s := &server{cfg: config.Default()}
+	for _, target := range []string{ts.URL, strings.Replace(ts.URL, "127.0.0.1", "localhost", 1)} {
+		resp := postValidateURL(t, s, target)
+
+		if resp.Blocked != true || resp.Reachable != false {
+			t.Errorf("%s was not blocked: got %+v", target, resp)
+		}
+
+		if !strings.Contains(resp.Message, "loopback") {
+			t.Errorf("wrong message: %v", resp.Message)
+		}
+	}
+}
+
+func TestValidateUrlBlockedRedirect(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		}))
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.Blocked != true {
+		t.Errorf("redirect was not blocked: got %+v", resp)
+	}
+
+	if !strings.Contains(resp.Message, "link-local") {
+		t.Errorf("wrong message: %v", resp.Message)
+	}
+}
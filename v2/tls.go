@@ -0,0 +1,131 @@
+package v2
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Classes of certificate verification errors
+const (
+	certExpired          = "expired"
+	certHostnameMismatch = "hostnameMismatch"
+	certUnknownAuthority = "unknownAuthority"
+	certIncompleteChain  = "incompleteChain"
+	certInvalid          = "invalid"
+)
+
+// TLSReport describes the certificate and connection of a HTTPS endpoint
+type TLSReport struct {
+	Error       string    `json:"error,omitempty"`
+	ErrorType   string    `json:"errorType,omitempty"`
+	Subject     string    `json:"subject"`
+	SANs        []string  `json:"sans,omitempty"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	DaysLeft    int       `json:"daysLeft"`
+	Warnings    []string  `json:"warnings,omitempty"`
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipherSuite"`
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// newTLSReport describes the connection state of a response. verifyErr is the
+// error of the verifying attempt, if it failed and the endpoint was fetched
+// without verification.
+func newTLSReport(state *tls.ConnectionState, verifyErr error, warnDays int, now time.Time) *TLSReport {
+	report := &TLSReport{
+		Version:     tlsVersions[state.Version],
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	if report.Version == "" {
+		report.Version = fmt.Sprintf("0x%04x", state.Version)
+	}
+
+	if errorType := classifyCertError(verifyErr, state.PeerCertificates); errorType != "" {
+		report.ErrorType = errorType
+		report.Error = unwrapURLError(verifyErr).Error()
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return report
+	}
+
+	leaf := state.PeerCertificates[0]
+	report.Subject = leaf.Subject.String()
+	report.Issuer = leaf.Issuer.String()
+	report.NotBefore = leaf.NotBefore
+	report.NotAfter = leaf.NotAfter
+	report.SANs = append(report.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		report.SANs = append(report.SANs, ip.String())
+	}
+
+	report.DaysLeft = int(leaf.NotAfter.Sub(now).Hours() / 24)
+	if leaf.NotAfter.After(now) && report.DaysLeft < warnDays {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("certificate expires within %d days", warnDays))
+	}
+	if state.Version < tls.VersionTLS12 {
+		report.Warnings = append(report.Warnings, report.Version+" is deprecated")
+	}
+
+	return report
+}
+
+// classifyCertError returns the class of a certificate verification error,
+// or an empty string if err isn't one
+func classifyCertError(err error, certs []*x509.Certificate) string {
+	if err == nil {
+		return ""
+	}
+
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) {
+		if invalid.Reason == x509.Expired {
+			return certExpired
+		}
+		return certInvalid
+	}
+
+	var hostname x509.HostnameError
+	if errors.As(err, &hostname) {
+		return certHostnameMismatch
+	}
+
+	var unknown x509.UnknownAuthorityError
+	var roots x509.SystemRootsError
+	if errors.As(err, &unknown) || errors.As(err, &roots) {
+		// a server sending only a leaf which isn't self-signed most likely
+		// misses the intermediate certificates
+		if len(certs) == 1 && !isSelfSigned(certs[0]) {
+			return certIncompleteChain
+		}
+		return certUnknownAuthority
+	}
+
+	return ""
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package v2
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/spaceapi/validator/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTLSTestServer() *httptest.Server {
+	return httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(validSpace))
+		}))
+}
+
+func TestValidateUrlTlsReport(t *testing.T) {
+	ts := newTLSTestServer()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	s := &server{cfg: testConfig(), rootCAs: roots}
+
+	resp := postValidateURL(t, s, ts.URL)
+
+	if resp.CertValid != true {
+		t.Errorf("cert check failed: got %v want %v", resp.CertValid, true)
+	}
+
+	if resp.TLS == nil {
+		t.Fatalf("tls report missing")
+	}
+
+	if resp.TLS.Error != "" || resp.TLS.ErrorType != "" {
+		t.Errorf("unexpected verification error: %v (%v)", resp.TLS.Error, resp.TLS.ErrorType)
+	}
+
+	if resp.TLS.Version != "TLS 1.3" || resp.TLS.CipherSuite == "" {
+		t.Errorf("wrong connection details: %v %v", resp.TLS.Version, resp.TLS.CipherSuite)
+	}
+
+	if !strings.Contains(strings.Join(resp.TLS.SANs, ","), "example.com") {
+		t.Errorf("wrong SANs: %v", resp.TLS.SANs)
+	}
+}
+
+func TestValidateUrlTlsHostnameMismatch(t *testing.T) {
+	ts := newTLSTestServer()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	s := &server{cfg: testConfig(), rootCAs: roots}
+
+	resp := postValidateURL(t, s, strings.Replace(ts.URL, "127.0.0.1", "localhost", 1))
+
+	if resp.TLS == nil || resp.TLS.ErrorType != certHostnameMismatch {
+		t.Errorf("wrong tls report: got %+v want error type %v", resp.TLS, certHostnameMismatch)
+	}
+}
+
+func TestValidateUrlTlsUnknownAuthority(t *testing.T) {
+	ts := newTLSTestServer()
+	defer ts.Close()
+
+	resp := postValidateURL(t, testServer, ts.URL)
+
+	if resp.TLS == nil || resp.TLS.ErrorType != certUnknownAuthority {
+		t.Fatalf("wrong tls report: got %+v want error type %v", resp.TLS, certUnknownAuthority)
+	}
+
+	if !strings.Contains(resp.TLS.Error, "x509") {
+		t.Errorf("wrong error: %v", resp.TLS.Error)
+	}
+}
+
+func TestNewTLSReportExpiry(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "status.example.com"},
+		Issuer:    pkix.Name{CommonName: "Example CA"},
+		DNSNames:  []string{"status.example.com"},
+		NotBefore: now.AddDate(0, -3, 0),
+		NotAfter:  now.AddDate(0, 0, 10),
+	}
+	state := &tls.ConnectionState{
+		Version:          tls.VersionTLS12,
+		CipherSuite:      tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		PeerCertificates: []*x509.Certificate{cert},
+	}
+
+	report := newTLSReport(state, nil, 14, now)
+
+	if report.DaysLeft != 10 {
+		t.Errorf("wrong days left: got %v want %v", report.DaysLeft, 10)
+	}
+
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "14 days") {
+		t.Errorf("wrong warnings: %v", report.Warnings)
+	}
+
+	if report.Subject != "CN=status.example.com" || report.Issuer != "CN=Example CA" {
+		t.Errorf("wrong subject or issuer: %v / %v", report.Subject, report.Issuer)
+	}
+
+	if report.CipherSuite != "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" {
+		t.Errorf("wrong cipher suite: %v", report.CipherSuite)
+	}
+}
+
+func TestClassifyCertError(t *testing.T) {
+	leaf := &x509.Certificate{RawSubject: []byte("leaf"), RawIssuer: []byte("intermediate")}
+
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{x509.CertificateInvalidError{Reason: x509.Expired}, certExpired},
+		{x509.CertificateInvalidError{Reason: x509.NotAuthorizedToSign}, certInvalid},
+		{x509.HostnameError{Certificate: leaf, Host: "example.com"}, certHostnameMismatch},
+		{x509.UnknownAuthorityError{}, certIncompleteChain},
+	}
+
+	for _, c := range cases {
+		if got := classifyCertError(c.err, []*x509.Certificate{leaf}); got != c.want {
+			t.Errorf("wrong class for %v: got %v want %v", c.err, got, c.want)
+		}
+	}
+}
+
+// fetchSamples returns the number of recorded endpoint fetches by result
+func fetchSamples(t *testing.T) map[string]int {
+	rr := forgeRequest(t, metrics.Handler().ServeHTTP, "GET", "/metrics", nil)
+
+	samples := map[string]int{}
+	for _, line := range strings.Split(rr.Body.String(), "\n") {
+		for _, result := range []string{"ok", "error"} {
+			prefix := `fetch_duration_seconds_count{result="` + result + `"} `
+			if i := strings.Index(line, prefix); i >= 0 {
+				n, err := strconv.Atoi(line[i+len(prefix):])
+				if err != nil {
+					t.Fatal(err)
+				}
+				samples[result] = n
+			}
+		}
+	}
+	return samples
+}
+
+func TestValidateUrlFetchMetrics(t *testing.T) {
+	invalidCert := newTLSTestServer()
+	defer invalidCert.Close()
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	tests := map[string]struct {
+		target string
+		result string
+	}{
+		"invalid certificate": {invalidCert.URL, "ok"},
+		"unreachable":         {gone.URL, "error"},
+	}
+
+	for name, test := range tests {
+		before := fetchSamples(t)
+		postValidateURL(t, testServer, test.target)
+		after := fetchSamples(t)
+
+		for _, result := range []string{"ok", "error"} {
+			want := 0
+			if result == test.result {
+				want = 1
+			}
+			if got := after[result] - before[result]; got != want {
+				t.Errorf("%s: wrong number of %s samples: got %v want %v", name, result, got, want)
+			}
+		}
+	}
+}
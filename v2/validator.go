@@ -1,15 +1,23 @@
 package v2
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
-	spaceapivalidator "github.com/spaceapi-community/go-spaceapi-validator"
+	"github.com/spaceapi/validator/config"
+	"github.com/spaceapi/validator/internal/httpbody"
+	"github.com/spaceapi/validator/lint"
+	"github.com/spaceapi/validator/metrics"
+	"github.com/spaceapi/validator/ratelimit"
+	"github.com/spaceapi/validator/schema"
 	"goji.io"
 	"goji.io/pat"
-	"golang.org/x/time/rate"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -23,61 +31,202 @@ type serverInfo struct {
 }
 
 type urlValidationRequest struct {
-	URL string `json:"url"`
+	URL      string   `json:"url"`
+	Versions []string `json:"versions"`
+	Deep     bool     `json:"deep"`
 }
 
-type urlValidationResponse struct {
-	Valid           bool          `json:"valid"`
-	Message         string        `json:"message,omitempty"`
-	IsHTTPS         bool          `json:"isHttps"`
-	HTTPSForward    bool          `json:"httpsForward"`
-	Reachable       bool          `json:"reachable"`
-	Cors            bool          `json:"cors"`
-	ContentType     bool          `json:"contentType"`
-	CertValid       bool          `json:"certValid"`
-	CheckedVersions []string      `json:"checkedVersions,omitempty"`
-	ValidatedJson   interface{}   `json:"validatedJson,omitempty"`
-	SchemaErrors    []schemaError `json:"schemaErrors,omitempty"`
+// ValidationOptions change how documents are validated
+type ValidationOptions struct {
+	// Versions forces validation against these SpaceAPI versions instead of
+	// the ones the document declares
+	Versions []string
+	// Schemas are validated against, the builtin schemas are used if nil
+	Schemas *schema.Set
+	// Deep makes ValidateURL fetch the resources the endpoint links to and
+	// verify its CORS setup with preflights from several origins
+	Deep bool
 }
 
-type schemaError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
+func (o ValidationOptions) schemas() *schema.Set {
+	if o.Schemas == nil {
+		return schema.Builtin()
+	}
+	return o.Schemas
+}
+
+// URLValidationResponse is the result of validating a SpaceAPI endpoint
+type URLValidationResponse struct {
+	Valid           bool             `json:"valid"`
+	Message         string           `json:"message,omitempty"`
+	IsHTTPS         bool             `json:"isHttps"`
+	HTTPSForward    bool             `json:"httpsForward"`
+	Reachable       bool             `json:"reachable"`
+	Reachability    *Reachability    `json:"reachability,omitempty"`
+	Cors            bool             `json:"cors"`
+	ContentType     bool             `json:"contentType"`
+	CertValid       bool             `json:"certValid"`
+	Blocked         bool             `json:"blocked"`
+	BodyTooLarge    bool             `json:"bodyTooLarge"`
+	ParseError      *ParseError      `json:"parseError,omitempty"`
+	TLS             *TLSReport       `json:"tls,omitempty"`
+	Redirects       *RedirectReport  `json:"redirects,omitempty"`
+	CORSChecks      []CORSCheck      `json:"corsChecks,omitempty"`
+	CheckedVersions []string         `json:"checkedVersions,omitempty"`
+	ValidatedJson   interface{}      `json:"validatedJson,omitempty"`
+	SchemaErrors    []SchemaError    `json:"schemaErrors,omitempty"`
+	Warnings        []lint.Warning   `json:"warnings,omitempty"`
+	Links           []LinkReport     `json:"links,omitempty"`
+	Patch           []PatchOperation `json:"patch,omitempty"`
+}
+
+// SchemaError describes a field violating the SpaceAPI schema
+type SchemaError struct {
+	Field      string `json:"field"`
+	Message    string `json:"message"`
+	Pointer    string `json:"pointer,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
 }
 
-type jsonValidationResponse struct {
-	Valid           bool          `json:"valid"`
-	Message         string        `json:"message"`
-	CheckedVersions []string      `json:"checkedVersions,omitempty"`
-	ValidatedJson   interface{}   `json:"validatedJson,omitempty"`
-	SchemaErrors    []schemaError `json:"schemaErrors,omitempty"`
+// String formats the error with its position and suggested fix, if they are
+// known
+func (e SchemaError) String() string {
+	message := e.Field + ": " + e.Message
+	if e.Suggestion != "" {
+		message += " (" + e.Suggestion + ")"
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, message)
+	}
+	return message
 }
 
-// GetSubMux returns the versions subrouter
-func GetSubMux() *goji.Mux {
+// JSONValidationResponse is the result of validating a SpaceAPI document
+type JSONValidationResponse struct {
+	Valid           bool             `json:"valid"`
+	Message         string           `json:"message"`
+	CheckedVersions []string         `json:"checkedVersions,omitempty"`
+	ValidatedJson   interface{}      `json:"validatedJson,omitempty"`
+	SchemaErrors    []SchemaError    `json:"schemaErrors,omitempty"`
+	Warnings        []lint.Warning   `json:"warnings,omitempty"`
+	Patch           []PatchOperation `json:"patch,omitempty"`
+}
+
+// server holds the configuration the handlers depend on
+type server struct {
+	cfg     config.Config
+	schemas *schema.Registry
+	// rootCAs replaces the system roots when verifying certificates, if set
+	rootCAs *x509.CertPool
+	jobs    *jobQueue
+	// batchLimit and directoryLimit charge the endpoints of a batch or a
+	// directory, if set
+	batchLimit     *ratelimit.Limiter
+	directoryLimit *ratelimit.Limiter
+}
+
+// GetSubMux returns the versions subrouter. Validation jobs are run with
+// contexts derived from ctx, so cancelling it aborts them. The returned
+// function stops running further jobs and waits for the running ones.
+func GetSubMux(ctx context.Context, cfg config.Config, schemas *schema.Registry) (*goji.Mux, func()) {
+	s := newServer(cfg, schemas)
+	s.jobs = newJobQueue(ctx, cfg.JobWorkers, cfg.JobQueueSize, cfg.JobRetention, s.runJob)
+	s.batchLimit = ratelimit.New(cfg.RateLimit, cfg.RateBurst, cfg.LimitOptions())
+	s.directoryLimit = ratelimit.New(cfg.RateLimit, cfg.RateBurst, cfg.LimitOptions())
+
 	v2 := goji.SubMux()
 	v2.HandleFunc(pat.Get("/"), info)
-	v2.HandleFunc(pat.Post("/validateJSON"), validateJSON)
+	v2.HandleFunc(pat.Get("/schemas"), s.listSchemas)
+	v2.HandleFunc(pat.Get("/schemas/:version"), s.getSchema)
+	v2.Handle(
+		pat.Post("/validateJSON"),
+		endpoint(
+			metrics.RouteV2ValidateJSON,
+			ratelimit.New(cfg.JSONRateLimit, cfg.JSONRateBurst, cfg.LimitOptions()),
+			httpbody.MaxBytes(http.HandlerFunc(s.validateJSON), cfg.MaxRequestBodySize),
+		),
+	)
 	v2.Handle(
 		pat.Post("/validateURL"),
-		limit(
-			http.HandlerFunc(validateURL),
-			rate.NewLimiter(200, 500), // (rate, burst)
+		endpoint(
+			metrics.RouteV2ValidateURL,
+			ratelimit.New(cfg.RateLimit, cfg.RateBurst, cfg.LimitOptions()),
+			httpbody.MaxBytes(http.HandlerFunc(s.validateURL), cfg.MaxRequestBodySize),
+		),
+	)
+	v2.Handle(
+		pat.Post("/migrate"),
+		endpoint(
+			metrics.RouteV2Migrate,
+			ratelimit.New(cfg.JSONRateLimit, cfg.JSONRateBurst, cfg.LimitOptions()),
+			httpbody.MaxBytes(http.HandlerFunc(s.migrate), cfg.MaxRequestBodySize),
+		),
+	)
+	v2.Handle(
+		pat.Post("/validateBatch"),
+		endpoint(
+			metrics.RouteV2Batch,
+			s.batchLimit,
+			httpbody.MaxBytes(http.HandlerFunc(s.validateBatch), cfg.MaxRequestBodySize),
+		),
+	)
+	v2.Handle(
+		pat.Post("/validateDirectory"),
+		endpoint(
+			metrics.RouteV2Directory,
+			s.directoryLimit,
+			httpbody.MaxBytes(http.HandlerFunc(s.validateDirectory), cfg.MaxRequestBodySize),
+		),
+	)
+	v2.Handle(
+		pat.Post("/jobs"),
+		endpoint(
+			metrics.RouteV2Jobs,
+			ratelimit.New(cfg.RateLimit, cfg.RateBurst, cfg.LimitOptions()),
+			httpbody.MaxBytes(http.HandlerFunc(s.submitJob), cfg.MaxRequestBodySize),
+		),
+	)
+	v2.Handle(
+		pat.Get("/jobs/:id"),
+		endpoint(
+			metrics.RouteV2Job,
+			ratelimit.New(cfg.JSONRateLimit, cfg.JSONRateBurst, cfg.LimitOptions()),
+			http.HandlerFunc(s.getJob),
 		),
 	)
 
-	return v2
+	return v2, s.jobs.stop
 }
 
-func limit(next http.Handler, limiter *rate.Limiter) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if limiter.Allow() == false {
-			http.Error(w, http.StatusText(429), http.StatusTooManyRequests)
-			return
-		}
+func newServer(cfg config.Config, schemas *schema.Registry) *server {
+	return &server{cfg: cfg, schemas: schemas}
+}
 
-		next.ServeHTTP(w, r)
-	})
+// writeMargin is the part of the WriteTimeout reserved for writing a
+// response
+const writeMargin = time.Second * 5
+
+// writeBudget returns how long handlers may take to validate, so that the
+// response is written before the WriteTimeout. It is 0 without WriteTimeout.
+func writeBudget(cfg config.Config) time.Duration {
+	if cfg.WriteTimeout <= 0 {
+		return 0
+	}
+	budget := cfg.WriteTimeout - writeMargin
+	if budget < cfg.WriteTimeout/2 {
+		budget = cfg.WriteTimeout / 2
+	}
+	return budget
+}
+
+// endpoint applies the rate limit to a route and instruments it
+func endpoint(route string, limiter *ratelimit.Limiter, next http.Handler) http.Handler {
+	limiter.OnReject = func(*http.Request) {
+		metrics.RateLimited(route)
+	}
+	return metrics.Instrument(route, limiter.Handler(next))
 }
 
 func info(writer http.ResponseWriter, _ *http.Request) {
@@ -94,18 +243,17 @@ func info(writer http.ResponseWriter, _ *http.Request) {
 	}
 }
 
-func validateURL(writer http.ResponseWriter, request *http.Request) {
+func (s *server) validateURL(writer http.ResponseWriter, request *http.Request) {
 	if request.Body == nil {
 		http.Error(writer, "body can't be empty", http.StatusBadRequest)
 		return
 	}
 
 	var valReq urlValidationRequest
-	var valRes urlValidationResponse
 
-	err := json.NewDecoder(request.Body).Decode(&valReq)
+	err := httpbody.DecodeStrict(request.Body, &valReq)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusBadRequest)
+		httpbody.Error(writer, err, http.StatusBadRequest)
 		return
 	}
 
@@ -114,66 +262,136 @@ func validateURL(writer http.ResponseWriter, request *http.Request) {
 		http.Error(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
-	valRes.IsHTTPS = u.Scheme == "https"
 
-	header, body, err := fetchURL(&valRes, u, false)
+	schemas := s.schemas.Current()
+	versions, err := schemas.ParseVersions(valReq.Versions)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if header != nil {
-		checkHeader(&valRes, header)
+	// deep checks may take longer than the WriteTimeout, checks which are
+	// still running when the budget is used up are reported as failed
+	ctx := request.Context()
+	if budget := writeBudget(s.cfg); budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
 	}
 
-	if body == "" {
-		writer.Header().Add("Content-Type", "application/json")
-		err = json.NewEncoder(writer).Encode(valRes)
-		if err != nil {
-			http.Error(writer, err.Error(), http.StatusInternalServerError)
-		}
-
+	valRes, err := s.checkURL(ctx, u, ValidationOptions{Versions: versions, Schemas: schemas, Deep: valReq.Deep})
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		http.Error(writer, "the endpoint took too long, use /v2/jobs to validate slow endpoints", http.StatusGatewayTimeout)
 		return
 	}
-	var raw map[string]interface{}
-	if err := json.Unmarshal([]byte(body), &raw); err != nil {
-		http.Error(writer, fmt.Sprintf("Unmarshal failed: error: %s, data: %s", err.Error(), body), http.StatusInternalServerError)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	valRes.ValidatedJson = raw
+	recordChecks(metrics.RouteV2ValidateURL, valRes)
 
-	res, err := spaceapivalidator.Validate(body)
+	writer.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(valRes)
 	if err != nil {
-		http.Error(writer, fmt.Sprintf("Validate failed: error: %s", err.Error()), http.StatusInternalServerError)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
 		return
 	}
+}
 
-	for _, schema := range res.Schemas {
-		valRes.CheckedVersions = append(valRes.CheckedVersions, schema.Version)
+// ValidateURL fetches a SpaceAPI endpoint, checks the server setup and
+// validates the served document. It builds the response returned by
+// /v2/validateURL.
+func ValidateURL(ctx context.Context, cfg config.Config, u *url.URL, opts ValidationOptions) (URLValidationResponse, error) {
+	return newServer(cfg, nil).checkURL(ctx, u, opts)
+}
+
+func (s *server) checkURL(ctx context.Context, u *url.URL, opts ValidationOptions) (URLValidationResponse, error) {
+	var valRes URLValidationResponse
+	valRes.IsHTTPS = u.Scheme == "https"
+
+	// one sample per validation, including the retry without certificate
+	// verification
+	start := time.Now()
+	header, body, err := s.fetchURL(ctx, &valRes, u, nil)
+	metrics.Fetch(time.Since(start).Seconds(), err == nil && valRes.Reachable)
+	if err != nil {
+		return valRes, err
 	}
 
-	valRes.Valid = res.Valid
-	var errMsg string
-	for _, validatorError := range res.Errors {
-		errMsg = errMsg + validatorError.Context + ": " + validatorError.Description + "\n"
-		valRes.SchemaErrors = append(valRes.SchemaErrors, schemaError{
-			Field:   validatorError.Context,
-			Message: validatorError.Description,
-		})
+	if header != nil {
+		checkHeader(&valRes, header, s.cfg.Origin)
+		if opts.Deep {
+			valRes.CORSChecks = s.checkCORS(ctx, finalURL(u, valRes.Redirects), !valRes.CertValid)
+		}
 	}
-	valRes.Message = errMsg
 
-	writer.Header().Add("Content-Type", "application/json")
-	err = json.NewEncoder(writer).Encode(valRes)
+	// an endpoint which is unreachable or serves too much has no body to
+	// parse, an empty body is reported as a parse error
+	if header == nil || valRes.BodyTooLarge {
+		return valRes, nil
+	}
+
+	raw, parseErr := parseObject([]byte(body))
+	if parseErr != nil {
+		valRes.ParseError = parseErr
+		valRes.Message = "endpoint body is not a JSON object: " + parseErr.Error
+		return valRes, nil
+	}
+	valRes.ValidatedJson = raw
+
+	res, err := opts.schemas().Validate([]byte(body), opts.Versions)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
-		return
+		return valRes, fmt.Errorf("Validate failed: error: %s", err.Error())
+	}
+
+	valRes.Valid = res.Valid
+	valRes.CheckedVersions, valRes.SchemaErrors, valRes.Message, valRes.Patch = schemaResult(res, []byte(body))
+	// linked resources are only fetched in deep mode, the lint rules
+	// checking links reuse their results
+	env := lint.Env{Now: time.Now()}
+	if opts.Deep {
+		valRes.Links = s.checkLinks(ctx, lint.Document(raw))
+		env.CheckLink = checkedLinks(valRes.Links)
+	}
+	valRes.Warnings = lintDocument(ctx, raw, []byte(body), env)
+
+	return valRes, nil
+}
+
+// recordChecks updates the metrics with the outcome of an URL validation
+func recordChecks(route string, valRes URLValidationResponse) {
+	metrics.Validation(route, valRes.Valid)
+	for _, check := range failedChecks(valRes) {
+		metrics.CheckFailed(check)
 	}
 }
 
-func checkHeader(response *urlValidationResponse, header http.Header) {
+// failedChecks returns the server checks an URL validation failed. Server
+// checks are only counted for reachable endpoints.
+func failedChecks(valRes URLValidationResponse) []string {
+	if !valRes.Reachable {
+		return []string{"reachable"}
+	}
+
+	var failed []string
+	if !valRes.Cors {
+		failed = append(failed, "cors")
+	}
+	if !valRes.ContentType {
+		failed = append(failed, "contentType")
+	}
+	if (valRes.IsHTTPS || valRes.HTTPSForward) && !valRes.CertValid {
+		failed = append(failed, "certValid")
+	}
+	return failed
+}
+
+// checkHeader checks the headers of the response to the GET sent from
+// origin. Cors is set if the response may be read by origin and ContentType
+// if it is served as JSON.
+func checkHeader(response *URLValidationResponse, header http.Header, origin string) {
 	acao := header.Get("Access-Control-Allow-Origin")
-	if acao == "*" || acao == "https://validator.spaceapi.io" {
+	if acao == "*" || acao == origin {
 		response.Cors = true
 	}
 
@@ -182,20 +400,33 @@ func checkHeader(response *urlValidationResponse, header http.Header) {
 	}
 }
 
-func fetchURL(validationResponse *urlValidationResponse, url *url.URL, skipVerify bool) (http.Header, string, error) {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
+// newClient returns a client for fetching endpoints which refuses to connect
+// to blocked destinations
+func (s *server) newClient(skipVerify bool) *http.Client {
+	dialer := &net.Dialer{Control: guardControl(s.cfg.FetchAllow)}
+	return &http.Client{
+		Timeout: s.cfg.FetchTimeout,
+		Transport: &http.Transport{
+			DialContext: dialer.DialContext,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: skipVerify,
+				RootCAs:            s.rootCAs,
+			},
+		},
 	}
+}
 
-	client := http.Client{
-		Timeout: time.Second * 10,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if req.URL.Scheme == "https" {
-				validationResponse.HTTPSForward = true
-			}
-			return nil
-		},
-		Transport: tr,
+// fetchURL fetches the endpoint. If the first attempt fails, it is retried
+// without certificate verification, passing the original error as verifyErr.
+func (s *server) fetchURL(ctx context.Context, validationResponse *URLValidationResponse, url *url.URL, verifyErr error) (http.Header, string, error) {
+	skipVerify := verifyErr != nil
+	redirects := &redirectTracker{max: s.cfg.MaxRedirects}
+	client := s.newClient(skipVerify)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme == "https" {
+			validationResponse.HTTPSForward = true
+		}
+		return redirects.check(req, via)
 	}
 	defer client.CloseIdleConnections()
 
@@ -204,15 +435,38 @@ func fetchURL(validationResponse *urlValidationResponse, url *url.URL, skipVerif
 		validationResponse.Reachable = false
 		return nil, "", err
 	}
+	req = req.WithContext(ctx)
 
-	req.Header.Add("Origin", "https://validator.spaceapi.io")
+	req.Header.Add("Origin", s.cfg.Origin)
 	response, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+
+		if errors.Is(err, errRedirectLoop) || errors.Is(err, errTooManyRedirects) {
+			validationResponse.Reachable = false
+			validationResponse.Reachability = fetchFailure(err)
+			validationResponse.Redirects = redirects.result(nil)
+			validationResponse.Message = unwrapURLError(err).Error()
+			return nil, "", nil
+		}
+
+		var blocked *blockedError
+		if errors.As(err, &blocked) {
+			validationResponse.Reachable = false
+			validationResponse.Reachability = fetchFailure(err)
+			validationResponse.Blocked = true
+			validationResponse.Message = blocked.Error()
+			return nil, "", nil
+		}
+
 		if skipVerify == false {
-			return fetchURL(validationResponse, url, true)
+			return s.fetchURL(ctx, validationResponse, url, err)
 		}
 
 		validationResponse.Reachable = false
+		validationResponse.Reachability = fetchFailure(err)
 		return nil, "", nil
 	}
 
@@ -222,65 +476,147 @@ func fetchURL(validationResponse *urlValidationResponse, url *url.URL, skipVerif
 			panic(err)
 		}
 	}()
+	validationResponse.Redirects = redirects.result(response)
 
 	if response.StatusCode >= 400 {
 		validationResponse.Reachable = false
-		_, _ = io.Copy(ioutil.Discard, response.Body)
+		body, _ := ioutil.ReadAll(io.LimitReader(response.Body, excerptSize+1))
+		validationResponse.Reachability = statusFailure(response, body)
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, s.cfg.MaxFetchBodySize))
 		return nil, "", nil
 	}
 
-	bodyArray, _ := ioutil.ReadAll(response.Body)
+	bodyArray, _ := ioutil.ReadAll(io.LimitReader(response.Body, s.cfg.MaxFetchBodySize+1))
 	validationResponse.Reachable = true
 	validationResponse.CertValid = (validationResponse.IsHTTPS || validationResponse.HTTPSForward) && !skipVerify
+	if response.TLS != nil {
+		validationResponse.TLS = newTLSReport(response.TLS, verifyErr, s.cfg.CertWarningDays, time.Now())
+	}
+
+	if int64(len(bodyArray)) > s.cfg.MaxFetchBodySize {
+		validationResponse.BodyTooLarge = true
+		validationResponse.Message = fmt.Sprintf("endpoint body exceeds the maximum size of %d bytes", s.cfg.MaxFetchBodySize)
+		return response.Header, "", nil
+	}
+
 	return response.Header, string(bodyArray), nil
 }
 
-func validateJSON(writer http.ResponseWriter, request *http.Request) {
+func (s *server) validateJSON(writer http.ResponseWriter, request *http.Request) {
 	if request.Body == nil {
 		http.Error(writer, "body can't be empty", http.StatusBadRequest)
 		return
 	}
+	format, err := negotiateFormat(request.URL.Query().Get("format"), request.Header.Get("Accept"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schemas := s.schemas.Current()
+	versions, err := schemas.ParseVersions(schema.SplitVersions(request.URL.Query().Get("versions")))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	body, err := ioutil.ReadAll(request.Body)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		httpbody.Error(writer, err, http.StatusInternalServerError)
 		return
 	}
 
-	res, err := spaceapivalidator.Validate(string(body))
+	resp, err := ValidateJSON(body, ValidationOptions{Versions: versions, Schemas: schemas})
 	if err != nil {
 		http.Error(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
+	metrics.Validation(metrics.RouteV2ValidateJSON, resp.Valid)
+
+	if format != FormatJSON {
+		name := request.URL.Query().Get("file")
+		if name == "" {
+			name = "spaceapi.json"
+		}
+
+		writer.Header().Add("Content-Type", ContentType(format))
+		err = WriteReports(writer, format, []Report{{Name: name, Result: resp}})
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(resp)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ValidateJSON validates a SpaceAPI document and builds the response returned
+// by /v2/validateJSON. An error is returned if body is not a JSON object.
+func ValidateJSON(body []byte, opts ValidationOptions) (JSONValidationResponse, error) {
+	res, err := opts.schemas().Validate(body, opts.Versions)
+	if err != nil {
+		return JSONValidationResponse{}, err
+	}
 
 	var raw map[string]interface{}
 	if err := json.Unmarshal(body, &raw); err != nil {
-		http.Error(writer, err.Error(), http.StatusBadRequest)
-		return
+		return JSONValidationResponse{}, err
 	}
 
-	resp := jsonValidationResponse{
+	resp := JSONValidationResponse{
 		Valid:         res.Valid,
 		ValidatedJson: raw,
 	}
+	resp.CheckedVersions, resp.SchemaErrors, resp.Message, resp.Patch = schemaResult(res, body)
+	resp.Warnings = lintDocument(context.Background(), raw, body, lint.Env{Now: time.Now()})
 
+	return resp, nil
+}
+
+// schemaResult converts the result of the schema validation into the checked
+// versions, schema errors, error message and JSON patch of the responses. The
+// schema errors are annotated with their position in the validated document
+// and a suggested fix, if there is one.
+func schemaResult(res schema.Result, document []byte) ([]string, []SchemaError, string, []PatchOperation) {
+	var versions []string
 	for _, schema := range res.Schemas {
-		resp.CheckedVersions = append(resp.CheckedVersions, schema.Version)
+		versions = append(versions, schema.Version)
+	}
+
+	offsets, err := valueOffsets(document)
+	if err != nil {
+		offsets = map[string]int{}
 	}
+	var doc lint.Document
+	_ = json.Unmarshal(document, &doc)
 
+	var schemaErrors []SchemaError
 	var errMsg string
-	for _, validatorError := range res.Errors {
+	var patch patchBuilder
+	for i, validatorError := range res.Errors {
 		errMsg = errMsg + validatorError.Context + ": " + validatorError.Description + "\n"
-		resp.SchemaErrors = append(resp.SchemaErrors, schemaError{
+		schemaError := SchemaError{
 			Field:   validatorError.Context,
 			Message: validatorError.Description,
-		})
+		}
+		if pointer, ok := contextPointer(validatorError.Context, offsets); ok {
+			schemaError.Pointer = pointer
+			schemaError.Line, schemaError.Column = lineColumn(document, offsets[pointer])
+
+			if fix, ok := suggestFix(doc, pointer, res.Details[i]); ok {
+				schemaError.Suggestion = fix.suggestion
+				if fix.op != nil {
+					patch.add(*fix.op)
+				}
+			}
+		}
+		schemaErrors = append(schemaErrors, schemaError)
 	}
-	resp.Message = errMsg
 
-	writer.Header().Add("Content-Type", "application/json")
-	err = json.NewEncoder(writer).Encode(resp)
-	if err != nil {
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	return versions, schemaErrors, errMsg, patch.patch
 }
@@ -1,8 +1,13 @@
 package v2
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/spaceapi/validator/config"
+	"goji.io"
+	"goji.io/pat"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -51,26 +56,63 @@ var invalidSpaceApiVersion = `{
 	]
 }`
 
-func forgeValidateJSONRequest(t *testing.T, body io.Reader) *httptest.ResponseRecorder {
-	req, err := http.NewRequest("POST", "/v2/validateJSON", body)
+var testServer = &server{cfg: testConfig()}
+
+// testConfig allows fetching from the loopback test servers
+func testConfig() config.Config {
+	cfg := config.Default()
+	for _, cidr := range []string{"127.0.0.0/8", "::1/128"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		cfg.FetchAllow = append(cfg.FetchAllow, network)
+	}
+	return cfg
+}
+
+// forgeRequest serves a request with handler, which may be a handler method
+// of a server or the ServeHTTP method of a mux
+func forgeRequest(t *testing.T, handler http.HandlerFunc, method, target string, body io.Reader) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, target, body)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(validateJSON)
 	handler.ServeHTTP(rr, req)
 	return rr
 }
 
+func forgeValidateJSONRequest(t *testing.T, body io.Reader) *httptest.ResponseRecorder {
+	return forgeRequest(t, testServer.validateJSON, "POST", "/v2/validateJSON", body)
+}
+
 func forgeValidateURLRequest(t *testing.T, body io.Reader) *httptest.ResponseRecorder {
-	req, err := http.NewRequest("POST", "/v2/validateURL", body)
+	return forgeRequest(t, testServer.validateURL, "POST", "/v2/validateURL", body)
+}
+
+// postValidateURL validates target with the validateURL handler of s and
+// returns the decoded response
+func postValidateURL(t *testing.T, s *server, target string) URLValidationResponse {
+	rr := forgeRequest(t, s.validateURL, "POST", "/v2/validateURL", strings.NewReader(`{ "url": "`+target+`" }`))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v (%s)",
+			status, http.StatusOK, rr.Body.String())
+	}
+
+	resp := URLValidationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
 	}
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(validateURL)
-	handler.ServeHTTP(rr, req)
-	return rr
+	return resp
+}
+
+// newTestMux returns a root mux routing to the v2 subrouter, like main does,
+// and a func stopping its job workers
+func newTestMux() (*goji.Mux, func()) {
+	v2, stop := GetSubMux(context.Background(), testConfig(), nil)
+	root := goji.NewMux()
+	root.Handle(pat.New("/v2/*"), v2)
+	return root, stop
 }
 
 //// VALIDATE JSON ////
@@ -83,7 +125,7 @@ func TestValidateJsonWithValid(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	resp := jsonValidationResponse{}
+	resp := JSONValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -103,7 +145,7 @@ func TestValidateJsonWithInvalid(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	resp := jsonValidationResponse{}
+	resp := JSONValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -123,7 +165,7 @@ func TestValidateJsonWithInvalidSpaceApiVersion(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	resp := jsonValidationResponse{}
+	resp := JSONValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -169,7 +211,7 @@ func TestValidateUrlWithValid(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	resp := urlValidationResponse{}
+	resp := URLValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -189,7 +231,7 @@ func TestValidateUrlWithUnreachablePath(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	resp := urlValidationResponse{}
+	resp := URLValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -214,7 +256,7 @@ func TestValidateUrlWithUnreachableServer(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	resp := urlValidationResponse{}
+	resp := URLValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -269,7 +311,7 @@ func TestValidateUrlCors(t *testing.T) {
 
 	rr := forgeValidateURLRequest(t, strings.NewReader(`{ "url": "`+ts.URL+`" }`))
 
-	resp := urlValidationResponse{}
+	resp := URLValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -295,7 +337,7 @@ func TestValidateUrlCorsFalse(t *testing.T) {
 
 	rr := forgeValidateURLRequest(t, strings.NewReader(`{ "url": "`+ts.URL+`" }`))
 
-	resp := urlValidationResponse{}
+	resp := URLValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -320,7 +362,7 @@ func TestValidateUrlInvalidSpace(t *testing.T) {
 
 	rr := forgeValidateURLRequest(t, strings.NewReader(`{ "url": "`+ts.URL+`" }`))
 	t.Logf("%v", rr.Body)
-	resp := urlValidationResponse{}
+	resp := URLValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -350,7 +392,7 @@ func TestValidateUrlInvalidTls(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	resp := urlValidationResponse{}
+	resp := URLValidationResponse{}
 	err := json.NewDecoder(rr.Body).Decode(&resp)
 	if err != nil {
 		t.Fatal(err)
@@ -388,3 +430,90 @@ func TestServerInfo(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestValidateJsonSchemaErrorPosition(t *testing.T) {
+	rr := forgeValidateJSONRequest(t, strings.NewReader(invalidSpaceApiVersion))
+
+	resp := JSONValidationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, schemaError := range resp.SchemaErrors {
+		if schemaError.Field != "(root).api" {
+			continue
+		}
+
+		if schemaError.Pointer != "/api" || schemaError.Line != 2 || schemaError.Column != 9 {
+			t.Errorf("wrong position: got %v %v:%v want /api 2:9",
+				schemaError.Pointer, schemaError.Line, schemaError.Column)
+		}
+		return
+	}
+
+	t.Errorf("no schema error for (root).api in %+v", resp.SchemaErrors)
+}
+
+func TestValidateJsonForcedVersions(t *testing.T) {
+	rr := forgeRequest(t, testServer.validateJSON, "POST", "/v2/validateJSON?versions=13,15", strings.NewReader(validSpace))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := JSONValidationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(resp.CheckedVersions, ",") != "13,15" {
+		t.Errorf("handler checked wrong versions: got %v want %v", resp.CheckedVersions, []string{"13", "15"})
+	}
+	if resp.Valid != false {
+		t.Errorf("handler returned wrong response: got %v want %v", resp.Valid, false)
+	}
+}
+
+func TestValidateJsonUnknownVersion(t *testing.T) {
+	rr := forgeRequest(t, testServer.validateJSON, "POST", "/v2/validateJSON?versions=99", strings.NewReader(validSpace))
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func TestValidateUrlForcedVersions(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(validSpace))
+		}))
+	defer ts.Close()
+
+	rr := forgeValidateURLRequest(t, strings.NewReader(`{"url": "`+ts.URL+`", "versions": ["0.15"]}`))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	resp := URLValidationResponse{}
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(resp.CheckedVersions, ",") != "15" {
+		t.Errorf("handler checked wrong versions: got %v want %v", resp.CheckedVersions, []string{"15"})
+	}
+
+	rr = forgeValidateURLRequest(t, strings.NewReader(`{"url": "`+ts.URL+`", "versions": ["99"]}`))
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}